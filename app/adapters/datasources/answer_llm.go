@@ -0,0 +1,64 @@
+package datasources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
+
+type llmResponseRepo struct {
+	citationRepo repos.CitationRepo
+	llm          services.LLMClient
+}
+
+// NewLLMResponseRepo retrieves the context from the vector store and generates
+// the answer with the configured LLM rather than a Weaviate generative module,
+// so that the answer comes from the same provider and model for every vector
+// store.
+func NewLLMResponseRepo(citationRepo repos.CitationRepo, llm services.LLMClient) repos.StreamingResponseRepo {
+
+	return &llmResponseRepo{citationRepo, llm}
+}
+
+func (repo *llmResponseRepo) Get(ctx context.Context, query entities.Query) (*entities.Response, error) {
+
+	citations, err := repo.citationRepo.Retrieve(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	answer, err := repo.llm.Complete(ctx, services.CompletionRequest{Model: query.Model, Messages: prepareContextMessages(query.Question, citations)})
+
+	if err != nil {
+		return nil, fmt.Errorf("could not generate answer for question `%s`: %w", query.Question, err)
+	}
+
+	return &entities.Response{Query: query, Response: answer, Sources: citations}, nil
+}
+
+func (repo *llmResponseRepo) Stream(ctx context.Context, query entities.Query, handler entities.ResponseEventHandler) (*entities.Response, error) {
+
+	citations, err := repo.citationRepo.Retrieve(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handler(entities.ResponseEvent{Type: entities.ResponseEventSources, Sources: citations}); err != nil {
+		return nil, err
+	}
+
+	answer, err := repo.llm.Stream(ctx, services.CompletionRequest{Model: query.Model, Messages: prepareContextMessages(query.Question, citations)}, func(token string) error {
+		return handler(entities.ResponseEvent{Type: entities.ResponseEventToken, Token: token})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("could not stream answer for question `%s`: %w", query.Question, err)
+	}
+
+	return &entities.Response{Query: query, Response: answer, Sources: citations}, nil
+}
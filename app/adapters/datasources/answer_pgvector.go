@@ -0,0 +1,98 @@
+package datasources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
+
+type pgvectorCitationRepo struct {
+	conn             *pgxpool.Pool
+	embeddingService services.EmbeddingService
+	retrievalLimit   int
+	dimensions       int
+}
+
+func NewPGVectorCitationRepo(connPool *pgxpool.Pool, embeddingService services.EmbeddingService, retrievalLimit int, dimensions int) (repos.CitationRepo, error) {
+
+	if err := checkChunksTable(context.Background(), connPool, dimensions); err != nil {
+		return nil, err
+	}
+
+	return &pgvectorCitationRepo{connPool, embeddingService, retrievalLimit, dimensions}, nil
+}
+
+func (repo *pgvectorCitationRepo) Retrieve(ctx context.Context, query entities.Query) ([]entities.Citation, error) {
+
+	vectors, err := repo.embeddingService.Get(ctx, []string{repo.prepareSearchText(query)})
+
+	if err != nil {
+		return nil, fmt.Errorf("could not embed question `%s`: %w", query.Question, err)
+	}
+
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding for question `%s` but received %d", query.Question, len(vectors))
+	}
+
+	if len(vectors[0]) != repo.dimensions {
+		return nil, fmt.Errorf("embedding of question `%s` has %d dimensions instead of %d", query.Question, len(vectors[0]), repo.dimensions)
+	}
+
+	row, err := repo.conn.Query(ctx,
+		`SELECT domain_id, text, source, resource_id, embedding <=> $2::vector AS distance
+		FROM chunks WHERE domain_id = ANY($1)
+		ORDER BY distance LIMIT $3`,
+		query.DomainIds, formatVector(vectors[0]), repo.retrievalLimit)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve chunks from pgvector for question `%s`: %w", query.Question, err)
+	}
+
+	defer row.Close()
+
+	citations := make([]entities.Citation, 0)
+
+	for row.Next() {
+
+		citation := entities.Citation{Rank: len(citations) + 1}
+
+		if err = row.Scan(&citation.DomainId, &citation.Text, &citation.Source, &citation.ResourceId, &citation.Distance); err != nil {
+			return nil, fmt.Errorf("could not read chunk: %w", err)
+		}
+
+		// Same conversion Weaviate uses to report certainty for cosine distances.
+		citation.Certainty = 1 - citation.Distance/2
+
+		citations = append(citations, citation)
+	}
+
+	if err = row.Err(); err != nil {
+		return nil, fmt.Errorf("could not read chunks: %w", err)
+	}
+
+	if len(citations) < 1 {
+		return nil, fmt.Errorf("%w in domains %s", repos.NoCitationsError, strings.Join(query.DomainIds, ", "))
+	}
+
+	return citations, nil
+}
+
+func (repo *pgvectorCitationRepo) prepareSearchText(query entities.Query) string {
+
+	if len(query.Concepts) < 1 {
+		return query.Question
+	}
+
+	concepts := make([]string, 0, len(query.Concepts))
+
+	for _, concept := range query.Concepts {
+		concepts = append(concepts, string(concept))
+	}
+
+	return strings.Join(concepts, ", ")
+}
@@ -2,58 +2,151 @@ package datasources
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
 	"github.com/utsavgupta/knowledge-hub/app/repos"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
 	graphqlModels "github.com/weaviate/weaviate/entities/models"
 )
 
-type weaviateResponseRepo struct {
-	client *weaviate.Client
+var (
+	citationFields = []graphql.Field{
+		{Name: "text"},
+		{Name: "source"},
+		{Name: "_additional", Fields: []graphql.Field{{Name: "distance"}, {Name: "certainty"}}},
+	}
+	citationFieldsWithResource = append([]graphql.Field{{Name: "resource_id"}}, citationFields...)
+)
+
+type weaviateCitationRepo struct {
+	client         *weaviate.Client
+	retrievalLimit int
+	// resourceClasses holds the classes known to have a resource_id property.
+	resourceClasses sync.Map
 }
 
-func NewWeaviateResponseRepo(scheme string, host string, openaiAccessKey string) (repos.ResponseRepo, error) {
+func NewWeaviateCitationRepo(httpClient *http.Client, retrievalLimit int, scheme string, host string, vectorizerKeyHeader string, vectorizerKey string) (repos.CitationRepo, error) {
+
+	client, err := newWeaviateClient(httpClient, scheme, host, vectorizerKeyHeader, vectorizerKey)
 
-	cfg := weaviate.Config{
-		Host:    host,
-		Scheme:  scheme,
-		Headers: map[string]string{"X-OpenAI-Api-Key": openaiAccessKey},
+	if err != nil {
+		return nil, err
 	}
 
-	client, err := weaviate.NewClient(cfg)
+	return &weaviateCitationRepo{client: client, retrievalLimit: retrievalLimit}, nil
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("could not create Weaviate client with config %v: %w", cfg, err)
+// Retrieve queries the class of every domain in parallel and keeps the closest
+// objects across all of them. Domains that fail are skipped as long as at least
+// one of them succeeds.
+func (repo *weaviateCitationRepo) Retrieve(ctx context.Context, query entities.Query) ([]entities.Citation, error) {
+
+	results := make([][]entities.Citation, len(query.DomainIds))
+	errs := make([]error, len(query.DomainIds))
+
+	var wg sync.WaitGroup
+
+	for i, domainId := range query.DomainIds {
+
+		wg.Add(1)
+
+		go func(i int, domainId string) {
+			defer wg.Done()
+			results[i], errs[i] = repo.retrieveFromDomain(ctx, query, domainId)
+		}(i, domainId)
+	}
+
+	wg.Wait()
+
+	citations := make([]entities.Citation, 0, repo.retrievalLimit*len(query.DomainIds))
+
+	for i, err := range errs {
+
+		if err != nil {
+			logger.Instance().Warn(ctx, err.Error())
+			continue
+		}
+
+		citations = append(citations, results[i]...)
+	}
+
+	if len(citations) < 1 {
+
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w in domains %s", repos.NoCitationsError, strings.Join(query.DomainIds, ", "))
 	}
 
-	return &weaviateResponseRepo{client}, nil
+	return mergeCitations(citations, repo.retrievalLimit), nil
 }
 
-func (repo *weaviateResponseRepo) Get(ctx context.Context, query entities.Query) (*entities.Response, error) {
+func (repo *weaviateCitationRepo) retrieveFromDomain(ctx context.Context, query entities.Query, domainId string) ([]entities.Citation, error) {
+
+	fields, err := repo.citationFieldsOf(ctx, domainId)
+
+	if err != nil {
+		return nil, err
+	}
 
-	generativeSearchBuilder := graphql.NewGenerativeSearch().GroupedResult(query.Question)
 	nearTextArgumentBuilder := repo.prepareNearTextArgumentBuilder(query.Concepts)
 
 	gqlResponse, err := repo.client.GraphQL().
 		Get().
-		WithClassName(query.DomainId).
-		WithFields(graphql.Field{Name: "source"}).
-		WithGenerativeSearch(generativeSearchBuilder).
+		WithClassName(domainId).
+		WithFields(fields...).
 		WithNearText(nearTextArgumentBuilder).
-		WithLimit(5).Do(ctx)
+		WithLimit(repo.retrievalLimit).Do(ctx)
 
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve answer from Weaviate for question `%s`: %w", query.Question, err)
+		return nil, fmt.Errorf("could not retrieve context from Weaviate class %s for question `%s`: %w", domainId, query.Question, err)
 	}
 
-	return repo.prepareResponseFromGQLResponse(query, *gqlResponse)
+	gqlObjects, err := repo.extractObjectsFromGQLResponse(domainId, *gqlResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.extractCitations(domainId, gqlObjects)
+}
+
+// citationFieldsOf only asks for resource_id when the class has it, since
+// classes created before it was added to the schema fail queries for it. A
+// class is looked up again until the property shows up, which happens once a
+// resource of the domain is ingested.
+func (repo *weaviateCitationRepo) citationFieldsOf(ctx context.Context, domainId string) ([]graphql.Field, error) {
+
+	if _, ok := repo.resourceClasses.Load(domainId); ok {
+		return citationFieldsWithResource, nil
+	}
+
+	class, err := repo.client.Schema().ClassGetter().WithClassName(domainId).Do(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get the schema of Weaviate class %s: %w", domainId, err)
+	}
+
+	for _, property := range class.Properties {
+
+		if property.Name == "resource_id" {
+			repo.resourceClasses.Store(domainId, true)
+			return citationFieldsWithResource, nil
+		}
+	}
+
+	return citationFields, nil
 }
 
-func (repo *weaviateResponseRepo) prepareNearTextArgumentBuilder(concepts []entities.Concept) *graphql.NearTextArgumentBuilder {
+func (repo *weaviateCitationRepo) prepareNearTextArgumentBuilder(concepts []entities.Concept) *graphql.NearTextArgumentBuilder {
 
 	conceptsStr := make([]string, 0, len(concepts))
 
@@ -65,13 +158,13 @@ func (repo *weaviateResponseRepo) prepareNearTextArgumentBuilder(concepts []enti
 		WithConcepts(conceptsStr)
 }
 
-func (repo *weaviateResponseRepo) prepareAskArgBuilder(question string) *graphql.AskArgumentBuilder {
+func (repo *weaviateCitationRepo) prepareAskArgBuilder(question string) *graphql.AskArgumentBuilder {
 
 	return repo.client.GraphQL().AskArgBuilder().
 		WithQuestion(question)
 }
 
-func (repo *weaviateResponseRepo) prepareResponseFromGQLResponse(query entities.Query, gqlResponse graphqlModels.GraphQLResponse) (*entities.Response, error) {
+func (repo *weaviateCitationRepo) extractObjectsFromGQLResponse(domainId string, gqlResponse graphqlModels.GraphQLResponse) ([]map[string]any, error) {
 
 	if err := repo.extractErrorFromGQLResponse(gqlResponse); err != nil {
 
@@ -84,46 +177,59 @@ func (repo *weaviateResponseRepo) prepareResponseFromGQLResponse(query entities.
 		return nil, fmt.Errorf("cannot find root get: %s", gqlResponse.Data)
 	}
 
-	gqlDomains, ok := gqlGet[query.DomainId].([]any)
+	gqlDomains, ok := gqlGet[domainId].([]any)
 
 	if !ok {
 		return nil, fmt.Errorf("cannot find domains list: %s", gqlGet)
 	}
 
-	gqlDomain, _ := gqlDomains[0].(map[string]any)
+	gqlObjects := make([]map[string]any, 0, len(gqlDomains))
 
-	if !ok {
-		return nil, fmt.Errorf("cannot find domain %s: %s", query.DomainId, gqlDomains)
-	}
+	for _, gqlDomain := range gqlDomains {
 
-	addl, _ := gqlDomain["_additional"].(map[string]any)
+		gqlObject, ok := gqlDomain.(map[string]any)
 
-	if !ok {
-		return nil, fmt.Errorf("cannot find additional object: %s", gqlDomain)
+		if !ok {
+			return nil, fmt.Errorf("cannot read object: %s", gqlDomain)
+		}
+
+		gqlObjects = append(gqlObjects, gqlObject)
 	}
 
-	generate, _ := addl["generate"].(map[string]any)
+	return gqlObjects, nil
+}
 
-	if !ok {
-		return nil, fmt.Errorf("cannot find generated string: %s", addl)
-	}
+func (repo *weaviateCitationRepo) extractCitations(domainId string, gqlObjects []map[string]any) ([]entities.Citation, error) {
 
-	groupedResult, _ := generate["groupedResult"].(string)
+	citations := make([]entities.Citation, 0, len(gqlObjects))
 
-	if !ok {
-		return nil, fmt.Errorf("cannot find grouped result: %s", generate)
-	}
+	for i, gqlObject := range gqlObjects {
 
-	source, _ := gqlDomain["source"].(string)
+		citation := entities.Citation{Rank: i + 1, DomainId: domainId}
 
-	if !ok {
-		return nil, fmt.Errorf("cannot find domains list: %s", gqlDomain)
+		if citation.Source, _ = gqlObject["source"].(string); len(citation.Source) < 1 {
+			return nil, fmt.Errorf("cannot find source: %s", gqlObject)
+		}
+
+		citation.Text, _ = gqlObject["text"].(string)
+
+		// Weaviate returns every number in GraphQL responses as a float.
+		if resourceId, ok := gqlObject["resource_id"].(float64); ok {
+			citation.ResourceId = int(resourceId)
+		}
+
+		if addl, ok := gqlObject["_additional"].(map[string]any); ok {
+			citation.Distance, _ = addl["distance"].(float64)
+			citation.Certainty, _ = addl["certainty"].(float64)
+		}
+
+		citations = append(citations, citation)
 	}
 
-	return &entities.Response{Query: query, Response: groupedResult, Sources: []string{source}}, nil
+	return citations, nil
 }
 
-func (repo *weaviateResponseRepo) extractErrorFromGQLResponse(gqlResponse graphqlModels.GraphQLResponse) error {
+func (repo *weaviateCitationRepo) extractErrorFromGQLResponse(gqlResponse graphqlModels.GraphQLResponse) error {
 
 	if len(gqlResponse.Errors) < 1 {
 
@@ -0,0 +1,108 @@
+package datasources
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+)
+
+const apiKeyColumns = "id, name, role, domain_ids, prefix, key_hash, created_at, revoked_at"
+
+type pgApiKeyRepo struct {
+	conn *pgxpool.Pool
+}
+
+func NewPGApiKeyRepo(connPool *pgxpool.Pool) (repos.ApiKeyRepo, error) {
+
+	return &pgApiKeyRepo{connPool}, nil
+}
+
+func (repo *pgApiKeyRepo) List(ctx context.Context) ([]entities.ApiKey, error) {
+
+	row, err := repo.conn.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY id")
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not list api keys: %w", err)
+	}
+
+	defer row.Close()
+
+	apiKeys := make([]entities.ApiKey, 0)
+
+	for row.Next() {
+
+		apiKey := entities.ApiKey{}
+
+		if err = scanApiKey(row, &apiKey); err != nil {
+
+			return nil, fmt.Errorf("could not read api key: %w", err)
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, row.Err()
+}
+
+func (repo *pgApiKeyRepo) GetByHash(ctx context.Context, keyHash string) (*entities.ApiKey, error) {
+
+	var apiKey entities.ApiKey
+
+	row, err := repo.conn.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", keyHash)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not fetch api key: %w", err)
+	}
+
+	defer row.Close()
+
+	if !row.Next() {
+
+		return nil, row.Err()
+	}
+
+	if err = scanApiKey(row, &apiKey); err != nil {
+
+		return nil, fmt.Errorf("could not fetch api key: %w", err)
+	}
+
+	return &apiKey, nil
+}
+
+func (repo *pgApiKeyRepo) Create(ctx context.Context, apiKey entities.ApiKey) (*entities.ApiKey, error) {
+
+	row := repo.conn.QueryRow(ctx,
+		"INSERT INTO api_keys (name, role, domain_ids, prefix, key_hash, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		apiKey.Name, apiKey.Role, apiKey.DomainIds, apiKey.Prefix, apiKey.KeyHash, apiKey.CreatedAt)
+
+	if err := row.Scan(&apiKey.Id); err != nil {
+
+		return nil, fmt.Errorf("could not create api key %s: %w", apiKey.Name, err)
+	}
+
+	return &apiKey, nil
+}
+
+func (repo *pgApiKeyRepo) Revoke(ctx context.Context, id int, revokedAt time.Time) (bool, error) {
+
+	tag, err := repo.conn.Exec(ctx, "UPDATE api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL", id, revokedAt)
+
+	if err != nil {
+
+		return false, fmt.Errorf("could not revoke api key with id %d: %w", id, err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func scanApiKey(row pgx.Row, apiKey *entities.ApiKey) error {
+
+	return row.Scan(&apiKey.Id, &apiKey.Name, &apiKey.Role, &apiKey.DomainIds, &apiKey.Prefix, &apiKey.KeyHash, &apiKey.CreatedAt, &apiKey.RevokedAt)
+}
@@ -0,0 +1,119 @@
+package datasources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+)
+
+type pgvectorChunkRepo struct {
+	conn       *pgxpool.Pool
+	dimensions int
+}
+
+// NewPGVectorChunkRepo fails when the chunks table has not been migrated,
+// which only happens while kh_vector_store is pgvector, or when its vectors
+// are not of the given size.
+func NewPGVectorChunkRepo(connPool *pgxpool.Pool, dimensions int) (repos.ChunkRepo, error) {
+
+	if err := checkChunksTable(context.Background(), connPool, dimensions); err != nil {
+		return nil, err
+	}
+
+	return &pgvectorChunkRepo{connPool, dimensions}, nil
+}
+
+func (repo *pgvectorChunkRepo) Replace(ctx context.Context, domainId string, resourceId int, chunks []entities.Chunk) error {
+
+	batch := &pgx.Batch{}
+	batch.Queue("DELETE FROM chunks WHERE domain_id = $1 AND resource_id = $2", domainId, resourceId)
+
+	for _, chunk := range chunks {
+
+		if len(chunk.Vector) != repo.dimensions {
+			return fmt.Errorf("chunk %d of resource %d has %d dimensions instead of %d", chunk.Index, resourceId, len(chunk.Vector), repo.dimensions)
+		}
+
+		batch.Queue("INSERT INTO chunks (domain_id, resource_id, text, source, embedding) VALUES ($1, $2, $3, $4, $5::vector)",
+			domainId, resourceId, chunk.Text, chunk.Source, formatVector(chunk.Vector))
+	}
+
+	err := pgx.BeginFunc(ctx, repo.conn, func(tx pgx.Tx) error {
+		return tx.SendBatch(ctx, batch).Close()
+	})
+
+	if err != nil {
+		return fmt.Errorf("could not replace chunks of resource %d in pgvector: %w", resourceId, err)
+	}
+
+	return nil
+}
+
+// CreateCollection is a no-op as every domain shares the chunks table.
+func (repo *pgvectorChunkRepo) CreateCollection(ctx context.Context, domainId string) error {
+
+	return nil
+}
+
+func (repo *pgvectorChunkRepo) DeleteCollection(ctx context.Context, domainId string) error {
+
+	if _, err := repo.conn.Exec(ctx, "DELETE FROM chunks WHERE domain_id = $1", domainId); err != nil {
+		return fmt.Errorf("could not delete chunks of domain %s: %w", domainId, err)
+	}
+
+	return nil
+}
+
+func (repo *pgvectorChunkRepo) DeleteByResource(ctx context.Context, domainId string, resourceId int) error {
+
+	if _, err := repo.conn.Exec(ctx, "DELETE FROM chunks WHERE domain_id = $1 AND resource_id = $2", domainId, resourceId); err != nil {
+		return fmt.Errorf("could not delete chunks of resource %d: %w", resourceId, err)
+	}
+
+	return nil
+}
+
+// checkChunksTable makes sure the chunks table exists and stores vectors of
+// the size the embedding model produces. The size of a vector column is kept
+// as its type modifier.
+func checkChunksTable(ctx context.Context, connPool *pgxpool.Pool, dimensions int) error {
+
+	var columnDimensions int
+
+	err := connPool.QueryRow(ctx,
+		"SELECT atttypmod FROM pg_attribute WHERE attrelid = to_regclass('chunks') AND attname = 'embedding'").Scan(&columnDimensions)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("the chunks table does not exist, run app migrate up with kh_vector_store=pgvector")
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not check the chunks table: %w", err)
+	}
+
+	if columnDimensions != dimensions {
+		return fmt.Errorf("the chunks table stores vectors of %d dimensions but kh_llm_embedding_dimensions is %d", columnDimensions, dimensions)
+	}
+
+	return nil
+}
+
+// formatVector renders a vector in the text representation understood by the
+// pgvector extension, e.g. [0.1,0.2,0.3].
+func formatVector(vector []float32) string {
+
+	values := make([]string, 0, len(vector))
+
+	for _, value := range vector {
+		values = append(values, strconv.FormatFloat(float64(value), 'f', -1, 32))
+	}
+
+	return "[" + strings.Join(values, ",") + "]"
+}
@@ -0,0 +1,274 @@
+package datasources
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+	graphqlModels "github.com/weaviate/weaviate/entities/models"
+)
+
+// maxChunkIdsPerQuery is the most objects Weaviate returns for a query by
+// default.
+const maxChunkIdsPerQuery = 10000
+
+var (
+	skipVectorization = map[string]any{"text2vec-openai": map[string]any{"skip": true}}
+)
+
+type weaviateChunkRepo struct {
+	client *weaviate.Client
+}
+
+func NewWeaviateChunkRepo(httpClient *http.Client, scheme string, host string, vectorizerKeyHeader string, vectorizerKey string) (repos.ChunkRepo, error) {
+
+	client, err := newWeaviateClient(httpClient, scheme, host, vectorizerKeyHeader, vectorizerKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &weaviateChunkRepo{client}, nil
+}
+
+// Replace writes the new chunks of a resource before removing the others, so
+// searches keep finding the resource while it is refreshed. Weaviate has no
+// transactions, so until the stale chunks are deleted searches may find old
+// and new chunks of the resource side by side. Chunk ids are derived from
+// their content hash, which makes retries idempotent. An empty list removes
+// every chunk of the resource.
+func (repo *weaviateChunkRepo) Replace(ctx context.Context, domainId string, resourceId int, chunks []entities.Chunk) error {
+
+	if len(chunks) < 1 {
+		return repo.DeleteByResource(ctx, domainId, resourceId)
+	}
+
+	objects := make([]*graphqlModels.Object, 0, len(chunks))
+
+	for _, chunk := range chunks {
+
+		objects = append(objects, &graphqlModels.Object{
+			ID:    chunkObjectId(chunk),
+			Class: domainId,
+			Properties: map[string]any{
+				"text":         chunk.Text,
+				"source":       chunk.Source,
+				"resource_id":  resourceId,
+				"content_hash": chunk.ContentHash,
+			},
+			Vector: chunk.Vector,
+		})
+	}
+
+	responses, err := repo.client.Batch().ObjectsBatcher().WithObjects(objects...).Do(ctx)
+
+	if err != nil {
+		return fmt.Errorf("could not index %d chunks in Weaviate: %w", len(chunks), err)
+	}
+
+	errs := make([]string, 0)
+
+	for _, response := range responses {
+
+		if response.Result == nil || response.Result.Errors == nil {
+			continue
+		}
+
+		for _, item := range response.Result.Errors.Error {
+			errs = append(errs, item.Message)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("could not index chunks in Weaviate: %s", strings.Join(errs, "\n"))
+	}
+
+	if err := repo.deleteStale(ctx, domainId, resourceId, objects); err != nil {
+		return fmt.Errorf("could not delete stale chunks of resource %d: %w", resourceId, err)
+	}
+
+	return nil
+}
+
+// deleteStale removes the chunks of a resource other than the given ones.
+// They are matched by id rather than by content hash, so that chunks written
+// before hashes were stored are removed too.
+func (repo *weaviateChunkRepo) deleteStale(ctx context.Context, domainId string, resourceId int, current []*graphqlModels.Object) error {
+
+	ids, err := repo.listChunkIds(ctx, domainId, resourceId)
+
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool, len(current))
+
+	for _, object := range current {
+		keep[object.ID.String()] = true
+	}
+
+	stale := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		if !keep[id] {
+			stale = append(stale, id)
+		}
+	}
+
+	if len(stale) < 1 {
+		return nil
+	}
+
+	return repo.deleteWhere(ctx, domainId, filters.Where().WithPath([]string{"id"}).WithOperator(filters.ContainsAny).WithValueText(stale...))
+}
+
+func (repo *weaviateChunkRepo) listChunkIds(ctx context.Context, domainId string, resourceId int) ([]string, error) {
+
+	gqlResponse, err := repo.client.GraphQL().
+		Get().
+		WithClassName(domainId).
+		WithFields(graphql.Field{Name: "_additional", Fields: []graphql.Field{{Name: "id"}}}).
+		WithWhere(repo.prepareResourceFilter(resourceId)).
+		WithLimit(maxChunkIdsPerQuery).Do(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not list chunks of resource %d: %w", resourceId, err)
+	}
+
+	if len(gqlResponse.Errors) > 0 {
+		return nil, fmt.Errorf("could not list chunks of resource %d: %s", resourceId, gqlResponse.Errors[0].Message)
+	}
+
+	gqlGet, _ := gqlResponse.Data["Get"].(map[string]any)
+	gqlObjects, _ := gqlGet[domainId].([]any)
+	ids := make([]string, 0, len(gqlObjects))
+
+	for _, gqlObject := range gqlObjects {
+
+		object, _ := gqlObject.(map[string]any)
+		addl, _ := object["_additional"].(map[string]any)
+
+		if id, ok := addl["id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (repo *weaviateChunkRepo) CreateCollection(ctx context.Context, domainId string) error {
+
+	exists, err := repo.client.Schema().ClassExistenceChecker().WithClassName(domainId).Do(ctx)
+
+	if err != nil {
+		return fmt.Errorf("could not check if Weaviate class %s exists: %w", domainId, err)
+	}
+
+	if exists {
+		return nil
+	}
+
+	class := &graphqlModels.Class{
+		Class:      domainId,
+		Vectorizer: "text2vec-openai",
+		ModuleConfig: map[string]any{
+			"text2vec-openai": map[string]any{"model": "ada", "modelVersion": "002", "type": "text", "vectorizeClassName": false},
+		},
+		Properties: []*graphqlModels.Property{
+			{Name: "text", DataType: []string{"text"}},
+			{Name: "source", DataType: []string{"text"}, ModuleConfig: skipVectorization},
+			{Name: "resource_id", DataType: []string{"int"}, ModuleConfig: skipVectorization},
+			{Name: "content_hash", DataType: []string{"text"}, Tokenization: "field", ModuleConfig: skipVectorization},
+		},
+	}
+
+	if err := repo.client.Schema().ClassCreator().WithClass(class).Do(ctx); err != nil {
+		return fmt.Errorf("could not create Weaviate class %s: %w", domainId, err)
+	}
+
+	return nil
+}
+
+func (repo *weaviateChunkRepo) DeleteCollection(ctx context.Context, domainId string) error {
+
+	exists, err := repo.client.Schema().ClassExistenceChecker().WithClassName(domainId).Do(ctx)
+
+	if err != nil {
+		return fmt.Errorf("could not check if Weaviate class %s exists: %w", domainId, err)
+	}
+
+	if !exists {
+		return nil
+	}
+
+	if err := repo.client.Schema().ClassDeleter().WithClassName(domainId).Do(ctx); err != nil {
+		return fmt.Errorf("could not delete Weaviate class %s: %w", domainId, err)
+	}
+
+	return nil
+}
+
+func (repo *weaviateChunkRepo) DeleteByResource(ctx context.Context, domainId string, resourceId int) error {
+
+	if err := repo.deleteWhere(ctx, domainId, repo.prepareResourceFilter(resourceId)); err != nil {
+		return fmt.Errorf("could not delete chunks of resource %d: %w", resourceId, err)
+	}
+
+	return nil
+}
+
+func (repo *weaviateChunkRepo) prepareResourceFilter(resourceId int) *filters.WhereBuilder {
+
+	return filters.Where().
+		WithPath([]string{"resource_id"}).
+		WithOperator(filters.Equal).
+		WithValueInt(int64(resourceId))
+}
+
+func (repo *weaviateChunkRepo) deleteWhere(ctx context.Context, domainId string, where *filters.WhereBuilder) error {
+
+	// Weaviate caps the number of objects removed by a single batch delete, so
+	// keep going until a batch comes back short.
+	for {
+
+		response, err := repo.client.Batch().ObjectsBatchDeleter().
+			WithClassName(domainId).
+			WithWhere(where).
+			Do(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		if response == nil || response.Results == nil {
+			return nil
+		}
+
+		if response.Results.Failed > 0 {
+			return fmt.Errorf("Weaviate failed to delete %d objects", response.Results.Failed)
+		}
+
+		if response.Results.Matches == 0 || response.Results.Matches < response.Results.Limit {
+			return nil
+		}
+	}
+}
+
+// chunkObjectId derives a stable UUID for a chunk from its resource, content
+// hash and position.
+func chunkObjectId(chunk entities.Chunk) strfmt.UUID {
+
+	b := sha256.Sum256([]byte(fmt.Sprintf("%s/%d/%s/%d", chunk.DomainId, chunk.ResourceId, chunk.ContentHash, chunk.Index)))
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return strfmt.UUID(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
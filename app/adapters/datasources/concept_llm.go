@@ -0,0 +1,53 @@
+package datasources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
+
+const conceptSystemPrompt = "Extract the concepts from the user's question and return the results in the form of an array. Escape the strings in the response."
+
+type conceptLLM struct {
+	llm services.LLMClient
+}
+
+func NewConceptLLM(llm services.LLMClient) services.ConceptService {
+
+	return &conceptLLM{llm}
+}
+
+func (service *conceptLLM) Get(ctx context.Context, question string) ([]entities.Concept, error) {
+
+	content, err := service.llm.Complete(ctx, service.prepareRequest(question))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.extractConcepts(content)
+}
+
+func (service *conceptLLM) extractConcepts(content string) ([]entities.Concept, error) {
+
+	var concepts []entities.Concept
+
+	if err := json.Unmarshal([]byte(content), &concepts); err != nil {
+		return nil, fmt.Errorf("could not unmarshal message content %v: %w", content, err)
+	}
+
+	return concepts, nil
+}
+
+func (service *conceptLLM) prepareRequest(question string) services.CompletionRequest {
+
+	return services.CompletionRequest{
+		Messages: []services.ChatMessage{
+			{Role: "system", Content: conceptSystemPrompt},
+			{Role: "user", Content: question},
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package datasources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
+
+const condenseSystemPrompt = "Given the conversation below and a follow-up question, rephrase the follow-up question to be a standalone question that can be understood without the conversation. Reply with the standalone question only."
+
+type condenseLLM struct {
+	llm services.LLMClient
+}
+
+func NewCondenseLLM(llm services.LLMClient) services.CondenseService {
+
+	return &condenseLLM{llm}
+}
+
+func (service *condenseLLM) Get(ctx context.Context, history []entities.Message, question string) (string, error) {
+
+	var conversation strings.Builder
+
+	for _, message := range history {
+		fmt.Fprintf(&conversation, "%s: %s\n", message.Role, message.Content)
+	}
+
+	messages := []services.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf("%s\n\n%s", condenseSystemPrompt, conversation.String())},
+		{Role: "user", Content: question},
+	}
+
+	standalone, err := service.llm.Complete(ctx, services.CompletionRequest{Messages: messages})
+
+	if err != nil {
+		return "", fmt.Errorf("could not condense question `%s`: %w", question, err)
+	}
+
+	return strings.TrimSpace(standalone), nil
+}
@@ -0,0 +1,142 @@
+package datasources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/utsavgupta/knowledge-hub/app/services"
+	"golang.org/x/net/html"
+)
+
+var skippedHTMLElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+	"svg":      true,
+	"head":     true,
+}
+
+type contentHTTP struct {
+	httpClient *http.Client
+	maxBytes   int64
+}
+
+// NewContentHTTP fetches plain text and HTML pages of at most maxBytes.
+func NewContentHTTP(httpClient *http.Client, maxBytes int64) services.ContentService {
+
+	return &contentHTTP{httpClient, maxBytes}
+}
+
+func (service *contentHTTP) Get(ctx context.Context, url string) (string, error) {
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return "", fmt.Errorf("could not create request object for %s: %w", url, err)
+	}
+
+	httpResponse, err := service.httpClient.Do(request)
+
+	if err != nil {
+		return "", fmt.Errorf("could not fetch %s: %w", url, err)
+	}
+
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s sent back status code %d", url, httpResponse.StatusCode)
+	}
+
+	if httpResponse.ContentLength > service.maxBytes {
+		return "", fmt.Errorf("content of %s is larger than %d bytes", url, service.maxBytes)
+	}
+
+	var mediaType string
+
+	// Unsupported content is turned down before it is downloaded, unless it
+	// has to be sniffed.
+	if contentType := httpResponse.Header.Get("Content-Type"); len(contentType) > 0 {
+
+		if mediaType, err = contentMediaType(contentType); err != nil {
+			return "", fmt.Errorf("%w for %s", err, url)
+		}
+	}
+
+	// One byte more than the limit tells content that is too large apart from
+	// content that is exactly as large as allowed.
+	b, err := io.ReadAll(io.LimitReader(httpResponse.Body, service.maxBytes+1))
+
+	if err != nil {
+		return "", fmt.Errorf("could not read content of %s: %w", url, err)
+	}
+
+	if int64(len(b)) > service.maxBytes {
+		return "", fmt.Errorf("content of %s is larger than %d bytes", url, service.maxBytes)
+	}
+
+	if len(mediaType) < 1 {
+
+		if mediaType, err = contentMediaType(http.DetectContentType(b)); err != nil {
+			return "", fmt.Errorf("%w for %s", err, url)
+		}
+	}
+
+	if mediaType == "text/plain" {
+		return string(b), nil
+	}
+
+	document, err := html.Parse(bytes.NewReader(b))
+
+	if err != nil {
+		return "", fmt.Errorf("could not parse html of %s: %w", url, err)
+	}
+
+	lines := make([]string, 0)
+	service.extractText(document, &lines)
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// contentMediaType accepts the media types that can be ingested, which are
+// plain text and HTML.
+func contentMediaType(contentType string) (string, error) {
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid content type %s", contentType)
+	}
+
+	switch mediaType {
+	case "text/plain", "text/html", "application/xhtml+xml":
+		return mediaType, nil
+	}
+
+	return "", fmt.Errorf("unsupported content type %s", contentType)
+}
+
+func (service *contentHTTP) extractText(node *html.Node, lines *[]string) {
+
+	if node.Type == html.ElementNode && skippedHTMLElements[node.Data] {
+		return
+	}
+
+	if node.Type == html.TextNode {
+
+		if text := strings.Join(strings.Fields(node.Data), " "); len(text) > 0 {
+			*lines = append(*lines, text)
+		}
+
+		return
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		service.extractText(child, lines)
+	}
+}
@@ -0,0 +1,143 @@
+package datasources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+)
+
+type pgConversationRepo struct {
+	conn *pgxpool.Pool
+}
+
+func NewPGConversationRepo(connPool *pgxpool.Pool) (repos.ConversationRepo, error) {
+
+	return &pgConversationRepo{connPool}, nil
+}
+
+func (repo *pgConversationRepo) Get(ctx context.Context, id int) (*entities.Conversation, error) {
+
+	var conversation entities.Conversation
+
+	row, err := repo.conn.Query(ctx, "SELECT id, domain_id, created_at, updated_at FROM conversations WHERE id = $1", id)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not fetch conversation with id %d: %w", id, err)
+	}
+
+	defer row.Close()
+
+	if !row.Next() {
+
+		return nil, row.Err()
+	}
+
+	if err = row.Scan(&conversation.Id, &conversation.DomainId, &conversation.CreatedAt, &conversation.UpdatedAt); err != nil {
+
+		return nil, fmt.Errorf("could not fetch conversation with id %d: %w", id, err)
+	}
+
+	row.Close()
+
+	if conversation.Messages, err = repo.listMessages(ctx, id); err != nil {
+
+		return nil, err
+	}
+
+	return &conversation, nil
+}
+
+func (repo *pgConversationRepo) Create(ctx context.Context, conversation entities.Conversation) (*entities.Conversation, error) {
+
+	row := repo.conn.QueryRow(ctx, "INSERT INTO conversations (domain_id, created_at) VALUES ($1, $2) RETURNING id", conversation.DomainId, conversation.CreatedAt)
+
+	if err := row.Scan(&conversation.Id); err != nil {
+
+		return nil, fmt.Errorf("could not create conversation %v: %w", conversation, err)
+	}
+
+	conversation.Messages = make([]entities.Message, 0)
+
+	return &conversation, nil
+}
+
+// AddMessages stores the messages of a conversation turn together, so a
+// question is never persisted without its answer.
+func (repo *pgConversationRepo) AddMessages(ctx context.Context, id int, messages []entities.Message) ([]entities.Message, error) {
+
+	err := pgx.BeginFunc(ctx, repo.conn, func(tx pgx.Tx) error {
+
+		for i, message := range messages {
+
+			sources, err := json.Marshal(message.Sources)
+
+			if err != nil {
+				return fmt.Errorf("could not marshall sources %v: %w", message.Sources, err)
+			}
+
+			if message.Sources == nil {
+				sources = []byte("[]")
+			}
+
+			row := tx.QueryRow(ctx,
+				"INSERT INTO conversation_messages (conversation_id, role, content, sources, created_at) VALUES ($1, $2, $3, $4::jsonb, $5) RETURNING id",
+				id, message.Role, message.Content, string(sources), message.CreatedAt)
+
+			if err = row.Scan(&messages[i].Id); err != nil {
+				return err
+			}
+
+			messages[i].ConversationId = id
+		}
+
+		_, err := tx.Exec(ctx, "UPDATE conversations SET updated_at = now() WHERE id = $1", id)
+		return err
+	})
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not add messages to conversation %d: %w", id, err)
+	}
+
+	return messages, nil
+}
+
+func (repo *pgConversationRepo) listMessages(ctx context.Context, id int) ([]entities.Message, error) {
+
+	row, err := repo.conn.Query(ctx, "SELECT id, conversation_id, role, content, sources, created_at FROM conversation_messages WHERE conversation_id = $1 ORDER BY id", id)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not list messages of conversation %d: %w", id, err)
+	}
+
+	defer row.Close()
+
+	messages := make([]entities.Message, 0)
+
+	for row.Next() {
+
+		var message entities.Message
+		var sources []byte
+
+		if err = row.Scan(&message.Id, &message.ConversationId, &message.Role, &message.Content, &sources, &message.CreatedAt); err != nil {
+
+			return nil, fmt.Errorf("could not read message: %w", err)
+		}
+
+		if err = json.Unmarshal(sources, &message.Sources); err != nil {
+
+			return nil, fmt.Errorf("could not unmarshal sources of message %d: %w", message.Id, err)
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, row.Err()
+}
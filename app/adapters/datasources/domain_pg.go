@@ -3,12 +3,22 @@ package datasources
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/utsavgupta/knowledge-hub/app/entities"
 	"github.com/utsavgupta/knowledge-hub/app/repos"
 )
 
+const domainColumns = "id, name, description, model, daily_token_budget, monthly_token_budget, created_at, updated_at"
+
+var domainSortColumns = map[string]pgSortColumn{
+	entities.SortById:        {"id", sortKeyText},
+	entities.SortByName:      {"name", sortKeyText},
+	entities.SortByCreatedAt: {"created_at", sortKeyTime},
+}
+
 type pgDomainRepo struct {
 	conn *pgxpool.Pool
 }
@@ -22,7 +32,7 @@ func (repo *pgDomainRepo) List(ctx context.Context) ([]entities.Domain, error) {
 
 	var domains []entities.Domain
 
-	row, err := repo.conn.Query(ctx, "SELECT id, name, description, created_at, updated_at FROM domains")
+	row, err := repo.conn.Query(ctx, "SELECT id, name, description, model, daily_token_budget, monthly_token_budget, created_at, updated_at FROM domains")
 
 	if err != nil {
 
@@ -37,7 +47,7 @@ func (repo *pgDomainRepo) List(ctx context.Context) ([]entities.Domain, error) {
 
 		domain := entities.Domain{}
 
-		if err = row.Scan(&domain.Id, &domain.Name, &domain.Description, &domain.CreatedAt, &domain.UpdatedAt); err != nil {
+		if err = row.Scan(&domain.Id, &domain.Name, &domain.Description, &domain.Model, &domain.DailyTokenBudget, &domain.MonthlyTokenBudget, &domain.CreatedAt, &domain.UpdatedAt); err != nil {
 
 			return nil, fmt.Errorf("could not read domain: %w", err)
 		}
@@ -48,11 +58,72 @@ func (repo *pgDomainRepo) List(ctx context.Context) ([]entities.Domain, error) {
 	return domains, nil
 }
 
+func (repo *pgDomainRepo) Find(ctx context.Context, filter entities.DomainFilter, page entities.PageRequest) (*entities.Page[entities.Domain], error) {
+
+	query := &pgQuery{}
+
+	if filter.Ids != nil {
+		query.where("id = ANY(" + query.arg(filter.Ids) + ")")
+	}
+
+	if len(filter.Search) > 0 {
+		query.contains(filter.Search, "id", "name")
+	}
+
+	result := &entities.Page[entities.Domain]{}
+
+	if err := repo.conn.QueryRow(ctx, "SELECT COUNT(*) FROM domains"+query.whereClause(), query.args...).Scan(&result.Total); err != nil {
+
+		return nil, fmt.Errorf("could not count domains: %w", err)
+	}
+
+	order, err := query.page(page, domainSortColumns, "id", sortKeyText)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not list domains: %w", err)
+	}
+
+	row, err := repo.conn.Query(ctx, "SELECT "+domainColumns+" FROM domains"+query.whereClause()+order, query.args...)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not list domains: %w", err)
+	}
+
+	defer row.Close()
+
+	domains := make([]entities.Domain, 0, page.Limit+1)
+
+	for row.Next() {
+
+		domain := entities.Domain{}
+
+		if err = row.Scan(&domain.Id, &domain.Name, &domain.Description, &domain.Model, &domain.DailyTokenBudget, &domain.MonthlyTokenBudget, &domain.CreatedAt, &domain.UpdatedAt); err != nil {
+
+			return nil, fmt.Errorf("could not read domain: %w", err)
+		}
+
+		domains = append(domains, domain)
+	}
+
+	if err = row.Err(); err != nil {
+
+		return nil, fmt.Errorf("could not list domains: %w", err)
+	}
+
+	result.Items, result.NextCursor = nextCursor(domains, page, func(domain entities.Domain) (string, string) {
+		return domainSortKey(domain, page.SortBy), domain.Id
+	})
+
+	return result, nil
+}
+
 func (repo *pgDomainRepo) Get(ctx context.Context, id string) (*entities.Domain, error) {
 
 	var domain entities.Domain
 
-	row, err := repo.conn.Query(ctx, "SELECT id, name, description, created_at, updated_at FROM domains WHERE id = $1", id)
+	row, err := repo.conn.Query(ctx, "SELECT id, name, description, model, daily_token_budget, monthly_token_budget, created_at, updated_at FROM domains WHERE id = $1", id)
 
 	if err != nil {
 
@@ -66,7 +137,7 @@ func (repo *pgDomainRepo) Get(ctx context.Context, id string) (*entities.Domain,
 		return nil, nil
 	}
 
-	if err = row.Scan(&domain.Id, &domain.Name, &domain.Description, &domain.CreatedAt, &domain.UpdatedAt); err != nil {
+	if err = row.Scan(&domain.Id, &domain.Name, &domain.Description, &domain.Model, &domain.DailyTokenBudget, &domain.MonthlyTokenBudget, &domain.CreatedAt, &domain.UpdatedAt); err != nil {
 
 		return nil, fmt.Errorf("could not fetch task with id %s: %w", id, err)
 	}
@@ -76,36 +147,72 @@ func (repo *pgDomainRepo) Get(ctx context.Context, id string) (*entities.Domain,
 
 func (repo *pgDomainRepo) Create(ctx context.Context, domain entities.Domain) (*entities.Domain, error) {
 
-	_, err := repo.conn.Exec(ctx, "INSERT INTO domains (id, name, description, created_at) VALUES ($1, $2, $3, $4)", domain.Id, domain.Name, domain.Description, domain.CreatedAt)
+	_, err := repo.conn.Exec(ctx, "INSERT INTO domains (id, name, description, model, daily_token_budget, monthly_token_budget, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", domain.Id, domain.Name, domain.Description, domain.Model, domain.DailyTokenBudget, domain.MonthlyTokenBudget, domain.CreatedAt)
 
 	if err != nil {
 
-		err = fmt.Errorf("could not create domain %v: %w", domain, err)
+		return nil, fmt.Errorf("could not create domain %v: %w", domain, err)
 	}
 
 	return &domain, nil
 }
 
-func (repo *pgDomainRepo) Update(ctx context.Context, domain entities.Domain) (*entities.Domain, error) {
+func (repo *pgDomainRepo) Update(ctx context.Context, domain entities.Domain, version time.Time) (*entities.Domain, error) {
 
-	_, err := repo.conn.Exec(ctx, "UPDATE domains SET name = $2, description = $3, updated_at = $4 WHERE id = $1", domain.Id, domain.Name, domain.Description, domain.UpdatedAt)
+	row, err := repo.conn.Query(ctx,
+		`UPDATE domains SET name = $2, description = $3, model = $4, daily_token_budget = $5, monthly_token_budget = $6, updated_at = $7
+		WHERE id = $1 AND COALESCE(updated_at, created_at) = $8
+		RETURNING id, name, description, model, daily_token_budget, monthly_token_budget, created_at, updated_at`,
+		domain.Id, domain.Name, domain.Description, domain.Model, domain.DailyTokenBudget, domain.MonthlyTokenBudget, domain.UpdatedAt, version)
 
 	if err != nil {
 
-		err = fmt.Errorf("could not update domain %v: %w", domain, err)
+		return nil, fmt.Errorf("could not update domain %v: %w", domain, err)
+	}
+
+	defer row.Close()
+
+	if !row.Next() {
+
+		return nil, row.Err()
 	}
 
-	return &domain, err
+	if err = row.Scan(&domain.Id, &domain.Name, &domain.Description, &domain.Model, &domain.DailyTokenBudget, &domain.MonthlyTokenBudget, &domain.CreatedAt, &domain.UpdatedAt); err != nil {
+
+		return nil, fmt.Errorf("could not read updated domain %s: %w", domain.Id, err)
+	}
+
+	return &domain, nil
 }
 
 func (repo *pgDomainRepo) Delete(ctx context.Context, id string) error {
 
-	var err error
+	err := pgx.BeginFunc(ctx, repo.conn, func(tx pgx.Tx) error {
 
-	if _, err = repo.conn.Exec(ctx, "DELETE FROM domains WHERE id = $1", id); err != nil {
+		if _, err := tx.Exec(ctx, "DELETE FROM resources WHERE domain_id = $1", id); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, "DELETE FROM domains WHERE id = $1", id)
+		return err
+	})
+
+	if err != nil {
 
 		err = fmt.Errorf("could not delete domain with id %s: %w", id, err)
 	}
 
 	return err
 }
+
+func domainSortKey(domain entities.Domain, sortBy string) string {
+
+	switch sortBy {
+	case entities.SortByName:
+		return domain.Name
+	case entities.SortByCreatedAt:
+		return formatTimeSortKey(domain.CreatedAt)
+	default:
+		return domain.Id
+	}
+}
@@ -0,0 +1,125 @@
+package datasources
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
+
+const (
+	embeddingsPath      = "embeddings"
+	embeddingsBatchSize = 100
+)
+
+type embeddingsRequestBody struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponseBody struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage usage `json:"usage"`
+}
+
+type embeddingOpenAI struct {
+	httpClient    *http.Client
+	config        LLMConfig
+	embeddingsURL string
+}
+
+// NewEmbeddingOpenAI embeds texts with the embeddings API of the same OpenAI
+// compatible provider as the LLM, unless EmbeddingBaseURL points elsewhere,
+// e.g. at another Azure OpenAI deployment.
+func NewEmbeddingOpenAI(httpClient *http.Client, config LLMConfig) (services.EmbeddingService, error) {
+
+	baseURL, err := url.Parse(config.EmbeddingBaseURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse embeddings base url %s: %w", config.EmbeddingBaseURL, err)
+	}
+
+	return &embeddingOpenAI{httpClient, config, baseURL.JoinPath(embeddingsPath).String()}, nil
+}
+
+func (service *embeddingOpenAI) Get(ctx context.Context, texts []string) ([][]float32, error) {
+
+	embeddings := make([][]float32, 0, len(texts))
+
+	for start := 0; start < len(texts); start += embeddingsBatchSize {
+
+		end := min(start+embeddingsBatchSize, len(texts))
+		batch, err := service.makeRequest(ctx, texts[start:end])
+
+		if err != nil {
+			return nil, err
+		}
+
+		embeddings = append(embeddings, batch...)
+	}
+
+	return embeddings, nil
+}
+
+func (service *embeddingOpenAI) makeRequest(ctx context.Context, texts []string) ([][]float32, error) {
+
+	body := embeddingsRequestBody{Model: service.config.EmbeddingModel, Input: texts}
+	bBody, err := json.Marshal(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not marshall embeddings request body: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, service.embeddingsURL, bytes.NewReader(bBody))
+
+	if err != nil {
+		return nil, fmt.Errorf("could not create request object for the embeddings API: %w", err)
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+	authorizeLLMRequest(request, service.config)
+
+	httpResponse, err := service.httpClient.Do(request)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not complete request to the embeddings API: %w", err)
+	}
+
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, openaiStatusError("embeddings API", httpResponse)
+	}
+
+	var response embeddingsResponseBody
+
+	if err = json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("could not parse response received from the embeddings API: %w", err)
+	}
+
+	services.AddTokenUsage(ctx, response.Usage.TotalTokens)
+
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings from the embeddings API but received %d", len(texts), len(response.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+
+	for _, item := range response.Data {
+
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("the embeddings API sent back an embedding with an invalid index %d", item.Index)
+		}
+
+		embeddings[item.Index] = item.Embedding
+	}
+
+	return embeddings, nil
+}
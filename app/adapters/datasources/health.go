@@ -0,0 +1,125 @@
+package datasources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+	"github.com/utsavgupta/knowledge-hub/app/tracing"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+)
+
+const llmModelsPath = "models"
+
+type pgHealthProbe struct {
+	conn *pgxpool.Pool
+}
+
+func NewPGHealthProbe(connPool *pgxpool.Pool) services.HealthProbe {
+
+	return &pgHealthProbe{connPool}
+}
+
+func (probe *pgHealthProbe) Name() string {
+
+	return "postgres"
+}
+
+func (probe *pgHealthProbe) Check(ctx context.Context) error {
+
+	if err := probe.conn.Ping(ctx); err != nil {
+		return fmt.Errorf("could not ping postgres: %w", err)
+	}
+
+	return nil
+}
+
+type weaviateHealthProbe struct {
+	client *weaviate.Client
+}
+
+func NewWeaviateHealthProbe(scheme string, host string, vectorizerKeyHeader string, vectorizerKey string) (services.HealthProbe, error) {
+
+	// The probe bypasses the outbound transport so that it neither retries
+	// nor trips the circuit breaker of Weaviate.
+	client, err := newWeaviateClient(&http.Client{Transport: tracing.NewTransport(http.DefaultTransport)}, scheme, host, vectorizerKeyHeader, vectorizerKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &weaviateHealthProbe{client}, nil
+}
+
+func (probe *weaviateHealthProbe) Name() string {
+
+	return "weaviate"
+}
+
+func (probe *weaviateHealthProbe) Check(ctx context.Context) error {
+
+	ready, err := probe.client.Misc().ReadyChecker().Do(ctx)
+
+	if err != nil {
+		return fmt.Errorf("could not check readiness of weaviate: %w", err)
+	}
+
+	if !ready {
+		return fmt.Errorf("weaviate is not ready")
+	}
+
+	return nil
+}
+
+type llmHealthProbe struct {
+	httpClient *http.Client
+	config     LLMConfig
+	modelsURL  string
+}
+
+// NewLLMHealthProbe lists the models of the LLM provider, which checks both
+// that it can be reached and that the api key is accepted without spending
+// any tokens.
+func NewLLMHealthProbe(httpClient *http.Client, config LLMConfig) (services.HealthProbe, error) {
+
+	baseURL, err := url.Parse(config.BaseURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse LLM base url %s: %w", config.BaseURL, err)
+	}
+
+	return &llmHealthProbe{httpClient, config, baseURL.JoinPath(llmModelsPath).String()}, nil
+}
+
+func (probe *llmHealthProbe) Name() string {
+
+	return "llm"
+}
+
+func (probe *llmHealthProbe) Check(ctx context.Context) error {
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, probe.modelsURL, nil)
+
+	if err != nil {
+		return fmt.Errorf("could not create request object for LLM: %w", err)
+	}
+
+	authorizeLLMRequest(request, probe.config)
+
+	response, err := probe.httpClient.Do(request)
+
+	if err != nil {
+		return fmt.Errorf("could not reach LLM: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("LLM sent back status code %d", response.StatusCode)
+	}
+
+	return nil
+}
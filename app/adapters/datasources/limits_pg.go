@@ -0,0 +1,122 @@
+package datasources
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+)
+
+type pgRateLimitRepo struct {
+	conn *pgxpool.Pool
+}
+
+func NewPGRateLimitRepo(connPool *pgxpool.Pool) (repos.RateLimitRepo, error) {
+
+	return &pgRateLimitRepo{connPool}, nil
+}
+
+// Take keeps the bucket in Postgres so that limits hold across app servers and
+// restarts. The row is locked while the bucket is refilled and a token taken.
+func (repo *pgRateLimitRepo) Take(ctx context.Context, key string, capacity float64, refillPerSecond float64, now time.Time) (time.Duration, error) {
+
+	var wait time.Duration
+
+	err := pgx.BeginFunc(ctx, repo.conn, func(tx pgx.Tx) error {
+
+		_, err := tx.Exec(ctx, "INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING", key, capacity, now)
+
+		if err != nil {
+			return err
+		}
+
+		var tokens float64
+		var updatedAt time.Time
+
+		if err = tx.QueryRow(ctx, "SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE", key).Scan(&tokens, &updatedAt); err != nil {
+			return err
+		}
+
+		tokens = min(capacity, tokens+max(now.Sub(updatedAt).Seconds(), 0)*refillPerSecond)
+
+		if tokens >= 1 {
+			tokens--
+		} else {
+			wait = time.Duration((1 - tokens) / refillPerSecond * float64(time.Second))
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE key = $1", key, tokens, now)
+		return err
+	})
+
+	if err != nil {
+
+		return 0, fmt.Errorf("could not take a token from rate limit bucket %s: %w", key, err)
+	}
+
+	return wait, nil
+}
+
+func (repo *pgRateLimitRepo) DeleteIdle(ctx context.Context, before time.Time) (int, error) {
+
+	tag, err := repo.conn.Exec(ctx, "DELETE FROM rate_limit_buckets WHERE updated_at < $1", before)
+
+	if err != nil {
+		return 0, fmt.Errorf("could not delete idle rate limit buckets: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+type pgBudgetRepo struct {
+	conn *pgxpool.Pool
+}
+
+func NewPGBudgetRepo(connPool *pgxpool.Pool) (repos.BudgetRepo, error) {
+
+	return &pgBudgetRepo{connPool}, nil
+}
+
+func (repo *pgBudgetRepo) Usage(ctx context.Context, domainId string, now time.Time) (int, int, error) {
+
+	var daily, monthly int
+	day, month := usagePeriods(now)
+
+	err := repo.conn.QueryRow(ctx,
+		"SELECT COALESCE(SUM(tokens) FILTER (WHERE day = $2), 0), COALESCE(SUM(tokens), 0) FROM llm_usage WHERE domain_id = $1 AND day >= $3",
+		domainId, day, month).Scan(&daily, &monthly)
+
+	if err != nil {
+
+		return 0, 0, fmt.Errorf("could not fetch token usage of domain %s: %w", domainId, err)
+	}
+
+	return daily, monthly, nil
+}
+
+func (repo *pgBudgetRepo) Record(ctx context.Context, domainId string, tokens int, now time.Time) error {
+
+	day, _ := usagePeriods(now)
+
+	_, err := repo.conn.Exec(ctx,
+		"INSERT INTO llm_usage (domain_id, day, tokens) VALUES ($1, $2, $3) ON CONFLICT (domain_id, day) DO UPDATE SET tokens = llm_usage.tokens + EXCLUDED.tokens",
+		domainId, day, tokens)
+
+	if err != nil {
+
+		return fmt.Errorf("could not record token usage of domain %s: %w", domainId, err)
+	}
+
+	return nil
+}
+
+// usagePeriods returns the first moment of the UTC day and month of now.
+func usagePeriods(now time.Time) (time.Time, time.Time) {
+
+	now = now.UTC()
+
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,321 @@
+package datasources
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
+
+const (
+	chatCompletionsPath   = "chat/completions"
+	chatStreamDataPrefix  = "data: "
+	chatStreamDone        = "[DONE]"
+	authorizationHeader   = "Authorization"
+	charsPerToken         = 4
+	insufficientQuota     = "insufficient_quota"
+	maxErrorBodyBytes     = 64 << 10
+	contextSystemPrompt   = "Answer the user's question using only the context provided below. If the context does not contain the answer, say that you do not know."
+	federatedSystemPrompt = "The context comes from several domains. Mention the domain of every piece of context you use in your answer."
+)
+
+// LLMConfig describes an OpenAI compatible chat completions API. BaseURL may
+// carry query parameters, such as the api-version required by Azure OpenAI.
+// The key is sent as a Bearer token when AuthHeader is Authorization and as is
+// otherwise.
+type LLMConfig struct {
+	BaseURL     string
+	Model       string
+	Temperature float64
+	AuthHeader  string
+	APIKey      string
+	// StreamUsage asks for the token usage of streamed completions, which not
+	// every compatible server supports. Usage is estimated otherwise.
+	StreamUsage      bool
+	EmbeddingBaseURL string
+	EmbeddingModel   string
+}
+
+type requestBody struct {
+	Model       string                 `json:"model"`
+	Messages    []services.ChatMessage `json:"messages"`
+	Temperature float64                `json:"temperature"`
+	Stream      bool                   `json:"stream,omitempty"`
+	// StreamOptions is an object so that it is left out unless usage is asked for.
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type usage struct {
+	TotalTokens int `json:"total_tokens"`
+}
+
+type responseBody struct {
+	Choices []struct {
+		Message services.ChatMessage `json:"message"`
+	} `json:"choices"`
+	Usage usage `json:"usage"`
+}
+
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *usage `json:"usage"`
+}
+
+type openaiCompatibleLLM struct {
+	httpClient     *http.Client
+	config         LLMConfig
+	completionsURL string
+}
+
+func NewOpenAICompatibleLLM(httpClient *http.Client, config LLMConfig) (services.LLMClient, error) {
+
+	baseURL, err := url.Parse(config.BaseURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse LLM base url %s: %w", config.BaseURL, err)
+	}
+
+	completionsURL := baseURL.JoinPath(chatCompletionsPath)
+
+	return &openaiCompatibleLLM{httpClient, config, completionsURL.String()}, nil
+}
+
+func (llm *openaiCompatibleLLM) Complete(ctx context.Context, completionRequest services.CompletionRequest) (string, error) {
+
+	httpResponse, err := llm.makeRequest(ctx, completionRequest, false)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer httpResponse.Body.Close()
+
+	var response responseBody
+
+	if err = json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("could not parse response received from LLM: %w", err)
+	}
+
+	services.AddTokenUsage(ctx, response.Usage.TotalTokens)
+
+	if len(response.Choices) < 1 {
+		return "", fmt.Errorf("LLM sent back no choices")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+func (llm *openaiCompatibleLLM) Stream(ctx context.Context, completionRequest services.CompletionRequest, onToken func(string) error) (string, error) {
+
+	httpResponse, err := llm.makeRequest(ctx, completionRequest, true)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer httpResponse.Body.Close()
+
+	var answer strings.Builder
+	var tokens *usage
+	scanner := bufio.NewScanner(httpResponse.Body)
+
+	for scanner.Scan() {
+
+		line := scanner.Text()
+
+		if !strings.HasPrefix(line, chatStreamDataPrefix) {
+			continue
+		}
+
+		data := strings.TrimPrefix(line, chatStreamDataPrefix)
+
+		if data == chatStreamDone {
+			break
+		}
+
+		var chunk chatStreamChunk
+
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("could not parse stream chunk received from LLM: %w", err)
+		}
+
+		if chunk.Usage != nil {
+			tokens = chunk.Usage
+		}
+
+		if len(chunk.Choices) < 1 || len(chunk.Choices[0].Delta.Content) < 1 {
+			continue
+		}
+
+		token := chunk.Choices[0].Delta.Content
+		answer.WriteString(token)
+
+		if err := onToken(token); err != nil {
+			return "", err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("could not read stream received from LLM: %w", err)
+	}
+
+	if tokens == nil {
+		tokens = &usage{estimateTokens(completionRequest.Messages, answer.String())}
+	}
+
+	services.AddTokenUsage(ctx, tokens.TotalTokens)
+
+	return answer.String(), nil
+}
+
+func (llm *openaiCompatibleLLM) makeRequest(ctx context.Context, completionRequest services.CompletionRequest, stream bool) (*http.Response, error) {
+
+	body := requestBody{
+		Model:       llm.config.Model,
+		Messages:    completionRequest.Messages,
+		Temperature: llm.config.Temperature,
+		Stream:      stream,
+	}
+
+	if len(completionRequest.Model) > 0 {
+		body.Model = completionRequest.Model
+	}
+
+	if stream && llm.config.StreamUsage {
+		body.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	bBody, err := json.Marshal(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not marshall request body %v: %w", body, err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, llm.completionsURL, bytes.NewReader(bBody))
+
+	if err != nil {
+		return nil, fmt.Errorf("could not create request object for LLM: %w", err)
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+
+	if stream {
+		request.Header.Add("Accept", "text/event-stream")
+	}
+
+	authorizeLLMRequest(request, llm.config)
+
+	httpResponse, err := llm.httpClient.Do(request)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not complete request to LLM: %w", err)
+	}
+
+	if httpResponse.StatusCode != http.StatusOK {
+		defer httpResponse.Body.Close()
+		return nil, openaiStatusError("LLM", httpResponse)
+	}
+
+	return httpResponse, nil
+}
+
+func authorizeLLMRequest(request *http.Request, config LLMConfig) {
+
+	if len(config.APIKey) < 1 {
+		return
+	}
+
+	if strings.EqualFold(config.AuthHeader, authorizationHeader) {
+		request.Header.Add(authorizationHeader, fmt.Sprintf("Bearer %s", config.APIKey))
+	} else {
+		request.Header.Add(config.AuthHeader, config.APIKey)
+	}
+}
+
+func estimateTokens(messages []services.ChatMessage, answer string) int {
+
+	chars := len(answer)
+
+	for _, message := range messages {
+		chars += len(message.Content)
+	}
+
+	return chars / charsPerToken
+}
+
+func prepareContextMessages(question string, citations []entities.Citation) []services.ChatMessage {
+
+	var retrieved strings.Builder
+	domains := make(map[string]bool)
+
+	for _, citation := range citations {
+		fmt.Fprintf(&retrieved, "[%d] domain: %s, source: %s\n%s\n\n", citation.Rank, citation.DomainId, citation.Source, citation.Text)
+		domains[citation.DomainId] = true
+	}
+
+	prompt := contextSystemPrompt
+
+	if len(domains) > 1 {
+		prompt = fmt.Sprintf("%s %s", prompt, federatedSystemPrompt)
+	}
+
+	return []services.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf("%s\n\n%s", prompt, retrieved.String())},
+		{Role: "user", Content: question},
+	}
+}
+
+// mergeCitations orders citations from several retrievals by distance, keeps
+// the closest limit of them and ranks them again.
+func mergeCitations(citations []entities.Citation, limit int) []entities.Citation {
+
+	sort.SliceStable(citations, func(i, j int) bool { return citations[i].Distance < citations[j].Distance })
+
+	if len(citations) > limit {
+		citations = citations[:limit]
+	}
+
+	for i := range citations {
+		citations[i].Rank = i + 1
+	}
+
+	return citations
+}
+
+// openaiStatusError describes a response other than 200 OK. A 429 becomes a
+// services.RateLimitError, which tells a used up quota apart by the error
+// code in the body.
+func openaiStatusError(upstream string, response *http.Response) error {
+
+	if response.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%s sent back status code %d", upstream, response.StatusCode)
+	}
+
+	var body struct {
+		Error struct {
+			Type string `json:"type"`
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+
+	json.NewDecoder(io.LimitReader(response.Body, maxErrorBodyBytes)).Decode(&body)
+
+	return &services.RateLimitError{Upstream: upstream, Quota: body.Error.Code == insufficientQuota || body.Error.Type == insufficientQuota}
+}
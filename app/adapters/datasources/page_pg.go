@@ -0,0 +1,147 @@
+package datasources
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+)
+
+const (
+	sortKeyText = iota
+	sortKeyInt
+	sortKeyTime
+)
+
+// pgSortColumn is a column entities can be sorted by, and the type its cursor
+// key is converted to.
+type pgSortColumn struct {
+	name string
+	kind int
+}
+
+// pgQuery collects the conditions of a query along with their arguments.
+type pgQuery struct {
+	conditions []string
+	args       []any
+}
+
+func (query *pgQuery) arg(value any) string {
+
+	query.args = append(query.args, value)
+	return "$" + strconv.Itoa(len(query.args))
+}
+
+func (query *pgQuery) where(condition string) {
+
+	query.conditions = append(query.conditions, condition)
+}
+
+func (query *pgQuery) whereClause() string {
+
+	if len(query.conditions) < 1 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(query.conditions, " AND ")
+}
+
+// contains matches a substring of any of the columns, case insensitively.
+func (query *pgQuery) contains(text string, columns ...string) {
+
+	pattern := query.arg("%" + escapeLike(text) + "%")
+	matches := make([]string, 0, len(columns))
+
+	for _, column := range columns {
+		matches = append(matches, column+" ILIKE "+pattern)
+	}
+
+	query.where("(" + strings.Join(matches, " OR ") + ")")
+}
+
+// page adds the keyset condition of the cursor and returns the ORDER BY and
+// LIMIT clauses. One more row than the limit is fetched to tell whether there
+// is a next page.
+func (query *pgQuery) page(page entities.PageRequest, sortColumns map[string]pgSortColumn, idColumn string, idKind int) (string, error) {
+
+	column, ok := sortColumns[page.SortBy]
+
+	if !ok {
+		return "", fmt.Errorf("cannot sort by %s", page.SortBy)
+	}
+
+	direction, comparison := "ASC", ">"
+
+	if page.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if page.Cursor != nil {
+
+		id, err := parseSortKey(page.Cursor.Id, idKind)
+
+		if err != nil {
+			return "", err
+		}
+
+		if column.name == idColumn {
+			query.where(fmt.Sprintf("%s %s %s", idColumn, comparison, query.arg(id)))
+		} else {
+
+			key, err := parseSortKey(page.Cursor.Key, column.kind)
+
+			if err != nil {
+				return "", err
+			}
+
+			query.where(fmt.Sprintf("(%s, %s) %s (%s, %s)", column.name, idColumn, comparison, query.arg(key), query.arg(id)))
+		}
+	}
+
+	order := fmt.Sprintf(" ORDER BY %s %s", column.name, direction)
+
+	if column.name != idColumn {
+		order += fmt.Sprintf(", %s %s", idColumn, direction)
+	}
+
+	return order + " LIMIT " + query.arg(page.Limit+1), nil
+}
+
+func parseSortKey(key string, kind int) (any, error) {
+
+	switch kind {
+	case sortKeyInt:
+		return strconv.Atoi(key)
+	case sortKeyTime:
+		return time.Parse(time.RFC3339Nano, key)
+	default:
+		return key, nil
+	}
+}
+
+func formatTimeSortKey(t time.Time) string {
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// nextCursor trims the extra row fetched by page and returns the cursor of
+// the last item left, if there are more.
+func nextCursor[T any](items []T, page entities.PageRequest, key func(T) (string, string)) ([]T, *string) {
+
+	if len(items) <= page.Limit {
+		return items, nil
+	}
+
+	items = items[:page.Limit]
+	sortKey, id := key(items[len(items)-1])
+	cursor := entities.Cursor{SortBy: page.SortBy, Descending: page.Descending, Key: sortKey, Id: id}.Encode()
+
+	return items, &cursor
+}
+
+func escapeLike(text string) string {
+
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+}
@@ -3,12 +3,25 @@ package datasources
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/utsavgupta/knowledge-hub/app/entities"
 	"github.com/utsavgupta/knowledge-hub/app/repos"
 )
 
+const resourceColumns = "id, name, description, status, url, domain_id, created_at, updated_at, ingestion_started_at, ingestion_completed_at, last_error, attempts, next_attempt_at, refresh_interval_seconds, content_hash"
+
+var resourceSortColumns = map[string]pgSortColumn{
+	entities.SortById:        {"id", sortKeyInt},
+	entities.SortByName:      {"name", sortKeyText},
+	entities.SortByUrl:       {"url", sortKeyText},
+	entities.SortByStatus:    {"status", sortKeyText},
+	entities.SortByCreatedAt: {"created_at", sortKeyTime},
+}
+
 type pgResourceRepo struct {
 	conn *pgxpool.Pool
 }
@@ -22,7 +35,7 @@ func (repo *pgResourceRepo) List(ctx context.Context, domainId string) ([]entiti
 
 	var resources []entities.Resource
 
-	row, err := repo.conn.Query(ctx, "SELECT id, name, description, status, url,domain_id, created_at, updated_at, ingestion_started_at, ingestion_completed_at FROM resources WHERE domain_id = $1", domainId)
+	row, err := repo.conn.Query(ctx, "SELECT "+resourceColumns+" FROM resources WHERE domain_id = $1", domainId)
 
 	if err != nil {
 
@@ -37,7 +50,7 @@ func (repo *pgResourceRepo) List(ctx context.Context, domainId string) ([]entiti
 
 		resource := entities.Resource{}
 
-		if err = row.Scan(&resource.Id, &resource.Name, &resource.Description, &resource.Status, &resource.Url, &resource.DomainId, &resource.CreatedAt, &resource.UpdatedAt, &resource.IngestionStartedAt, &resource.IngestionCompletedAt); err != nil {
+		if err = scanResource(row, &resource); err != nil {
 
 			return nil, fmt.Errorf("could not read resource: %w", err)
 		}
@@ -48,11 +61,94 @@ func (repo *pgResourceRepo) List(ctx context.Context, domainId string) ([]entiti
 	return resources, nil
 }
 
+func (repo *pgResourceRepo) Find(ctx context.Context, filter entities.ResourceFilter, page entities.PageRequest) (*entities.ResourcePage, error) {
+
+	query := &pgQuery{}
+	query.where("domain_id = " + query.arg(filter.DomainId))
+
+	if len(filter.Statuses) > 0 {
+		query.where("status = ANY(" + query.arg(filter.Statuses) + ")")
+	}
+
+	if filter.CreatedAfter != nil {
+		query.where("created_at >= " + query.arg(*filter.CreatedAfter))
+	}
+
+	if filter.CreatedBefore != nil {
+		query.where("created_at < " + query.arg(*filter.CreatedBefore))
+	}
+
+	if filter.IngestedAfter != nil {
+		query.where("ingestion_completed_at >= " + query.arg(*filter.IngestedAfter))
+	}
+
+	if filter.IngestedBefore != nil {
+		query.where("ingestion_completed_at < " + query.arg(*filter.IngestedBefore))
+	}
+
+	if len(filter.Search) > 0 {
+		query.contains(filter.Search, "name", "url")
+	}
+
+	result := &entities.ResourcePage{}
+
+	if err := repo.conn.QueryRow(ctx, "SELECT COUNT(*) FROM resources"+query.whereClause(), query.args...).Scan(&result.Total); err != nil {
+
+		return nil, fmt.Errorf("could not count resources: %w", err)
+	}
+
+	order, err := query.page(page, resourceSortColumns, "id", sortKeyInt)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not list resources: %w", err)
+	}
+
+	row, err := repo.conn.Query(ctx, "SELECT "+resourceColumns+" FROM resources"+query.whereClause()+order, query.args...)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not list resources: %w", err)
+	}
+
+	defer row.Close()
+
+	resources := make([]entities.Resource, 0, page.Limit+1)
+
+	for row.Next() {
+
+		resource := entities.Resource{}
+
+		if err = scanResource(row, &resource); err != nil {
+
+			return nil, fmt.Errorf("could not read resource: %w", err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	if err = row.Err(); err != nil {
+
+		return nil, fmt.Errorf("could not list resources: %w", err)
+	}
+
+	result.Items, result.NextCursor = nextCursor(resources, page, func(resource entities.Resource) (string, string) {
+		return resourceSortKey(resource, page.SortBy), strconv.Itoa(resource.Id)
+	})
+
+	if result.StatusCounts, err = repo.countByStatus(ctx, "WHERE domain_id = $1", filter.DomainId); err != nil {
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func (repo *pgResourceRepo) Get(ctx context.Context, id int) (*entities.Resource, error) {
 
 	var resource entities.Resource
 
-	row, err := repo.conn.Query(ctx, "SELECT id, name, description, status, url,domain_id, created_at, updated_at, ingestion_started_at, ingestion_completed_at FROM resources where id = $1", id)
+	row, err := repo.conn.Query(ctx, "SELECT "+resourceColumns+" FROM resources WHERE id = $1", id)
 
 	if err != nil {
 
@@ -66,7 +162,7 @@ func (repo *pgResourceRepo) Get(ctx context.Context, id int) (*entities.Resource
 		return nil, nil
 	}
 
-	if err = row.Scan(&resource.Id, &resource.Name, &resource.Description, &resource.Status, &resource.Url, &resource.DomainId, &resource.CreatedAt, &resource.UpdatedAt, &resource.IngestionStartedAt, &resource.IngestionCompletedAt); err != nil {
+	if err = scanResource(row, &resource); err != nil {
 
 		return nil, fmt.Errorf("could not fetch resource with id %d: %w", id, err)
 	}
@@ -76,7 +172,7 @@ func (repo *pgResourceRepo) Get(ctx context.Context, id int) (*entities.Resource
 
 func (repo *pgResourceRepo) Create(ctx context.Context, resource entities.Resource) (*entities.Resource, error) {
 
-	row := repo.conn.QueryRow(ctx, "INSERT INTO resources(name, description, status, url,domain_id, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id", resource.Name, resource.Description, entities.ResourceStatusNew, resource.Url, resource.DomainId, resource.CreatedAt)
+	row := repo.conn.QueryRow(ctx, "INSERT INTO resources(name, description, status, url,domain_id, created_at, refresh_interval_seconds) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id", resource.Name, resource.Description, entities.ResourceStatusNew, resource.Url, resource.DomainId, resource.CreatedAt, resource.RefreshIntervalSeconds)
 	idx := -1
 
 	if err := row.Scan(&idx); err != nil {
@@ -89,18 +185,34 @@ func (repo *pgResourceRepo) Create(ctx context.Context, resource entities.Resour
 	return &resource, nil
 }
 
-func (repo *pgResourceRepo) Update(ctx context.Context, resource entities.Resource) (*entities.Resource, error) {
+func (repo *pgResourceRepo) Update(ctx context.Context, resource entities.Resource, version time.Time) (*entities.Resource, error) {
 
-	_, err := repo.conn.Exec(ctx,
-		"UPDATE domains SET name = $2, description = $3 , updated_at = $4 WHERE id = $1",
-		resource.Id, resource.Name, resource.Description, resource.UpdatedAt)
+	row, err := repo.conn.Query(ctx,
+		`UPDATE resources SET name = $3, description = $4, url = $5, refresh_interval_seconds = $6, status = $7,
+			attempts = $8, last_error = $9, next_attempt_at = $10, content_hash = $11, updated_at = $12
+		WHERE id = $1 AND domain_id = $2 AND COALESCE(updated_at, created_at) = $13
+		RETURNING `+resourceColumns,
+		resource.Id, resource.DomainId, resource.Name, resource.Description, resource.Url, resource.RefreshIntervalSeconds, resource.Status,
+		resource.Attempts, resource.LastError, resource.NextAttemptAt, resource.ContentHash, resource.UpdatedAt, version)
 
 	if err != nil {
 
-		err = fmt.Errorf("could not update resource %v: %w", resource, err)
+		return nil, fmt.Errorf("could not update resource %v: %w", resource, err)
 	}
 
-	return &resource, err
+	defer row.Close()
+
+	if !row.Next() {
+
+		return nil, row.Err()
+	}
+
+	if err = scanResource(row, &resource); err != nil {
+
+		return nil, fmt.Errorf("could not read updated resource %d: %w", resource.Id, err)
+	}
+
+	return &resource, nil
 }
 
 func (repo *pgResourceRepo) Delete(ctx context.Context, domainId string, id int) error {
@@ -114,3 +226,170 @@ func (repo *pgResourceRepo) Delete(ctx context.Context, domainId string, id int)
 
 	return err
 }
+
+func (repo *pgResourceRepo) ClaimNew(ctx context.Context, startedAt time.Time) (*entities.Resource, error) {
+
+	var resource entities.Resource
+
+	row, err := repo.conn.Query(ctx,
+		`UPDATE resources SET status = $1, ingestion_started_at = $2, updated_at = $2, attempts = attempts + 1, next_attempt_at = NULL
+		WHERE id = (
+			SELECT id FROM resources
+			WHERE status = $3 OR (status = $4 AND next_attempt_at <= $2)
+			ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING `+resourceColumns,
+		entities.ResourceStatusIngesting, startedAt, entities.ResourceStatusNew, entities.ResourceStatusFailed)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not claim new resource: %w", err)
+	}
+
+	defer row.Close()
+
+	if !row.Next() {
+
+		return nil, row.Err()
+	}
+
+	if err = scanResource(row, &resource); err != nil {
+
+		return nil, fmt.Errorf("could not read claimed resource: %w", err)
+	}
+
+	return &resource, nil
+}
+
+func (repo *pgResourceRepo) UpdateIngestionStatus(ctx context.Context, resource entities.Resource) (*entities.Resource, error) {
+
+	_, err := repo.conn.Exec(ctx,
+		"UPDATE resources SET status = $2, ingestion_started_at = $3, ingestion_completed_at = $4, updated_at = $5, last_error = $6, next_attempt_at = $7, attempts = $8, content_hash = $9 WHERE id = $1",
+		resource.Id, resource.Status, resource.IngestionStartedAt, resource.IngestionCompletedAt, resource.UpdatedAt, resource.LastError, resource.NextAttemptAt, resource.Attempts, resource.ContentHash)
+
+	if err != nil {
+
+		err = fmt.Errorf("could not update ingestion status of resource %d: %w", resource.Id, err)
+	}
+
+	return &resource, err
+}
+
+// Requeue checks and changes the status in one statement, so that a worker
+// claiming the resource in the meantime is not overwritten.
+func (repo *pgResourceRepo) Requeue(ctx context.Context, domainId string, id int, resetContentHash bool, now time.Time) (*entities.Resource, error) {
+
+	var resource entities.Resource
+
+	row, err := repo.conn.Query(ctx,
+		`UPDATE resources SET status = $3, attempts = 0, last_error = NULL, next_attempt_at = NULL, updated_at = $4,
+			content_hash = CASE WHEN $5 THEN NULL ELSE content_hash END
+		WHERE id = $1 AND domain_id = $2 AND status <> $6
+		RETURNING `+resourceColumns,
+		id, domainId, entities.ResourceStatusNew, now, resetContentHash, entities.ResourceStatusIngesting)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not queue resource %d for ingestion: %w", id, err)
+	}
+
+	defer row.Close()
+
+	if !row.Next() {
+
+		return nil, row.Err()
+	}
+
+	if err = scanResource(row, &resource); err != nil {
+
+		return nil, fmt.Errorf("could not read queued resource %d: %w", id, err)
+	}
+
+	return &resource, nil
+}
+
+func (repo *pgResourceRepo) ReleaseStale(ctx context.Context, startedBefore time.Time, retryAt time.Time, maxAttempts int) (int, error) {
+
+	tag, err := repo.conn.Exec(ctx,
+		`UPDATE resources SET status = $1, last_error = $2, updated_at = $3,
+			next_attempt_at = CASE WHEN attempts < $4 THEN $3 ELSE NULL END
+		WHERE status = $5 AND ingestion_started_at < $6`,
+		entities.ResourceStatusFailed, "ingestion did not complete before the lease expired", retryAt, maxAttempts, entities.ResourceStatusIngesting, startedBefore)
+
+	if err != nil {
+
+		return 0, fmt.Errorf("could not release stale resources: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+func (repo *pgResourceRepo) RequeueDue(ctx context.Context, now time.Time) (int, error) {
+
+	tag, err := repo.conn.Exec(ctx,
+		`UPDATE resources SET status = $1, attempts = 0, next_attempt_at = NULL, updated_at = $2
+		WHERE status = $3 AND refresh_interval_seconds IS NOT NULL
+			AND ingestion_completed_at + refresh_interval_seconds * INTERVAL '1 second' <= $2`,
+		entities.ResourceStatusNew, now, entities.ResourceStatusIngested)
+
+	if err != nil {
+
+		return 0, fmt.Errorf("could not requeue resources due for a refresh: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+func (repo *pgResourceRepo) CountByStatus(ctx context.Context) (map[string]int, error) {
+
+	return repo.countByStatus(ctx, "")
+}
+
+func (repo *pgResourceRepo) countByStatus(ctx context.Context, where string, args ...any) (map[string]int, error) {
+
+	row, err := repo.conn.Query(ctx, "SELECT status, COUNT(*) FROM resources "+where+" GROUP BY status", args...)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("could not count resources by status: %w", err)
+	}
+
+	defer row.Close()
+
+	counts := make(map[string]int)
+
+	for row.Next() {
+
+		var status string
+		var count int
+
+		if err = row.Scan(&status, &count); err != nil {
+
+			return nil, fmt.Errorf("could not read resource count: %w", err)
+		}
+
+		counts[status] = count
+	}
+
+	return counts, row.Err()
+}
+
+func resourceSortKey(resource entities.Resource, sortBy string) string {
+
+	switch sortBy {
+	case entities.SortByName:
+		return resource.Name
+	case entities.SortByUrl:
+		return resource.Url
+	case entities.SortByStatus:
+		return resource.Status
+	case entities.SortByCreatedAt:
+		return formatTimeSortKey(resource.CreatedAt)
+	default:
+		return strconv.Itoa(resource.Id)
+	}
+}
+
+func scanResource(row pgx.Row, resource *entities.Resource) error {
+
+	return row.Scan(&resource.Id, &resource.Name, &resource.Description, &resource.Status, &resource.Url, &resource.DomainId, &resource.CreatedAt, &resource.UpdatedAt, &resource.IngestionStartedAt, &resource.IngestionCompletedAt, &resource.LastError, &resource.Attempts, &resource.NextAttemptAt, &resource.RefreshIntervalSeconds, &resource.ContentHash)
+}
@@ -0,0 +1,29 @@
+package datasources
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+)
+
+// newWeaviateClient passes the key of the vectorizer module on every request,
+// e.g. X-OpenAI-Api-Key for text2vec-openai or X-Azure-Api-Key for Azure OpenAI.
+// GraphQL queries and batches are sent with httpClient as plain HTTP requests.
+func newWeaviateClient(httpClient *http.Client, scheme string, host string, vectorizerKeyHeader string, vectorizerKey string) (*weaviate.Client, error) {
+
+	cfg := weaviate.Config{
+		Host:             host,
+		Scheme:           scheme,
+		Headers:          map[string]string{vectorizerKeyHeader: vectorizerKey},
+		ConnectionClient: httpClient,
+	}
+
+	client, err := weaviate.NewClient(cfg)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not create Weaviate client with config %v: %w", cfg, err)
+	}
+
+	return client, nil
+}
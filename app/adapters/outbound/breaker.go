@@ -0,0 +1,114 @@
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+)
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// breaker opens after a number of consecutive failures of an upstream host and
+// fails calls fast until the open duration has passed. It then lets a single
+// call through, which closes the breaker again if it succeeds.
+type breaker struct {
+	host             string
+	failureThreshold int
+	openDuration     time.Duration
+
+	mu        sync.Mutex
+	state     string
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(host string, failureThreshold int, openDuration time.Duration) *breaker {
+
+	return &breaker{host: host, failureThreshold: failureThreshold, openDuration: openDuration, state: BreakerClosed}
+}
+
+func (b *breaker) allow(ctx context.Context) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Now().After(b.openUntil) {
+		b.transition(ctx, BreakerHalfOpen)
+	}
+
+	switch b.state {
+	case BreakerOpen:
+		return fmt.Errorf("%w: %s until %s", ErrCircuitOpen, b.host, b.openUntil.Format(time.RFC3339))
+	case BreakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%w: %s is being probed", ErrCircuitOpen, b.host)
+		}
+
+		b.probing = true
+	}
+
+	return nil
+}
+
+func (b *breaker) record(ctx context.Context, success bool) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if success {
+		b.failures = 0
+
+		if b.state != BreakerClosed {
+			b.transition(ctx, BreakerClosed)
+		}
+
+		return
+	}
+
+	b.failures++
+
+	if b.state == BreakerHalfOpen || b.failures >= b.failureThreshold {
+		b.openUntil = time.Now().Add(b.openDuration)
+		b.transition(ctx, BreakerOpen)
+	}
+}
+
+// release ends a call without an outcome, e.g. one cancelled by the caller.
+func (b *breaker) release() {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *breaker) current() string {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *breaker) transition(ctx context.Context, state string) {
+
+	message := fmt.Sprintf("circuit breaker for %s moved from %s to %s", b.host, b.state, state)
+	b.state = state
+
+	if state == BreakerOpen {
+		logger.Instance().Warn(ctx, fmt.Sprintf("%s after %d consecutive failures", message, b.failures))
+		return
+	}
+
+	logger.Instance().Info(ctx, message)
+}
@@ -0,0 +1,113 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+)
+
+func TestMain(m *testing.M) {
+
+	logger.InitLogger(logger.NewZeroLogger())
+	os.Exit(m.Run())
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+
+	ctx := context.Background()
+	b := newBreaker("upstream", 2, time.Hour)
+
+	if err := b.allow(ctx); err != nil {
+		t.Fatalf("closed breaker refused a call: %v", err)
+	}
+
+	b.record(ctx, false)
+	b.record(ctx, true)
+	b.record(ctx, false)
+
+	if state := b.current(); state != BreakerClosed {
+		t.Fatalf("breaker is %s after failures separated by a success, want %s", state, BreakerClosed)
+	}
+
+	b.record(ctx, false)
+
+	if state := b.current(); state != BreakerOpen {
+		t.Fatalf("breaker is %s after 2 consecutive failures, want %s", state, BreakerOpen)
+	}
+
+	if err := b.allow(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open breaker answered %v, want %v", err, ErrCircuitOpen)
+	}
+}
+
+func TestBreakerProbesOnceWhenHalfOpen(t *testing.T) {
+
+	ctx := context.Background()
+	b := newBreaker("upstream", 1, 10*time.Millisecond)
+
+	b.record(ctx, false)
+	time.Sleep(20 * time.Millisecond)
+
+	if err := b.allow(ctx); err != nil {
+		t.Fatalf("breaker refused the probe after the open duration: %v", err)
+	}
+
+	if state := b.current(); state != BreakerHalfOpen {
+		t.Fatalf("breaker is %s while probing, want %s", state, BreakerHalfOpen)
+	}
+
+	if err := b.allow(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("half-open breaker let a second call through: %v", err)
+	}
+
+	b.record(ctx, true)
+
+	if state := b.current(); state != BreakerClosed {
+		t.Fatalf("breaker is %s after a successful probe, want %s", state, BreakerClosed)
+	}
+}
+
+func TestBreakerReopensWhenProbeFails(t *testing.T) {
+
+	ctx := context.Background()
+	b := newBreaker("upstream", 3, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		b.record(ctx, false)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := b.allow(ctx); err != nil {
+		t.Fatalf("breaker refused the probe after the open duration: %v", err)
+	}
+
+	b.record(ctx, false)
+
+	if state := b.current(); state != BreakerOpen {
+		t.Fatalf("breaker is %s after a failed probe, want %s", state, BreakerOpen)
+	}
+}
+
+func TestBreakerReleaseEndsProbe(t *testing.T) {
+
+	ctx := context.Background()
+	b := newBreaker("upstream", 1, 10*time.Millisecond)
+
+	b.record(ctx, false)
+	time.Sleep(20 * time.Millisecond)
+
+	if err := b.allow(ctx); err != nil {
+		t.Fatalf("breaker refused the probe after the open duration: %v", err)
+	}
+
+	b.release()
+
+	if err := b.allow(ctx); err != nil {
+		t.Fatalf("breaker refused a new probe after the previous one was released: %v", err)
+	}
+}
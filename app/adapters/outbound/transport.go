@@ -0,0 +1,267 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type Config struct {
+	// Timeout bounds how long a single attempt may wait for the response
+	// headers. Reading the body, which may be a long lived stream, is bound
+	// by the caller's context only.
+	Timeout          time.Duration
+	MaxAttempts      int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	FailureThreshold int
+	OpenDuration     time.Duration
+}
+
+// Transport retries calls that failed on the network or were answered with
+// 429 or 5xx using jittered exponential backoff, honouring Retry-After, and
+// keeps a circuit breaker per upstream host. Requests that are not idempotent
+// are retried in fewer cases, see retryable.
+type Transport struct {
+	next   http.RoundTripper
+	config Config
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func NewTransport(config Config) *Transport {
+
+	next := http.DefaultTransport.(*http.Transport).Clone()
+	next.ResponseHeaderTimeout = config.Timeout
+	next.DialContext = (&net.Dialer{Timeout: config.Timeout, KeepAlive: 30 * time.Second}).DialContext
+
+	return &Transport{next: next, config: config, breakers: make(map[string]*breaker)}
+}
+
+func (t *Transport) Client() *http.Client {
+
+	return &http.Client{Transport: t}
+}
+
+// Track creates the circuit breaker of a host before it is first called, so
+// that its state is reported from the start.
+func (t *Transport) Track(host string) {
+
+	t.breaker(host)
+}
+
+// BreakerStates reports the state of the circuit breaker of every host called so far.
+func (t *Transport) BreakerStates() map[string]string {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	states := make(map[string]string, len(t.breakers))
+
+	for host, b := range t.breakers {
+		states[host] = b.current()
+	}
+
+	return states
+}
+
+func (t *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+
+	ctx := request.Context()
+	b := t.breaker(request.URL.Host)
+
+	// Requests with a body can only be sent again if it can be rewound.
+	maxAttempts := t.config.MaxAttempts
+
+	if request.Body != nil && request.GetBody == nil {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+
+		if err := b.allow(ctx); err != nil {
+			return nil, err
+		}
+
+		response, err := t.next.RoundTrip(request)
+
+		if ctx.Err() != nil {
+			// The caller gave up, which says nothing about the health of the upstream.
+			b.release()
+			return response, err
+		}
+
+		b.record(ctx, err == nil && response.StatusCode < http.StatusInternalServerError)
+
+		if !retryable(request, response, err) || attempt >= maxAttempts {
+			return response, err
+		}
+
+		delay, ok := t.delay(attempt, response)
+
+		if !ok {
+			return response, err
+		}
+
+		logger.Instance().Warn(ctx, fmt.Sprintf("attempt %d of %s %s failed (%s), retrying in %s", attempt, request.Method, request.URL.Redacted(), describe(response, err), delay))
+
+		if response != nil {
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+
+		if request, err = rewind(request); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *Transport) breaker(host string) *breaker {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.breakers[host]
+
+	if !ok {
+		b = newBreaker(host, t.config.FailureThreshold, t.config.OpenDuration)
+		t.breakers[host] = b
+	}
+
+	return b
+}
+
+// delay returns how long to wait before the next attempt. Retry-After wins
+// over the backoff, but a wait longer than MaxDelay is not worth retrying.
+func (t *Transport) delay(attempt int, response *http.Response) (time.Duration, bool) {
+
+	if response != nil {
+
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+			return retryAfter, retryAfter <= t.config.MaxDelay
+		}
+	}
+
+	backoff := t.config.BaseDelay << (attempt - 1)
+
+	if backoff <= 0 || backoff > t.config.MaxDelay {
+		backoff = t.config.MaxDelay
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff) + 1)), true
+}
+
+// retryable decides whether a failed attempt is made again. Requests that are
+// not idempotent, such as the billed POSTs to the LLM and the embeddings API,
+// are only sent again when the upstream cannot have acted on them: no
+// connection could be made, or it turned them away with 429 or 503 and a
+// Retry-After.
+func retryable(request *http.Request, response *http.Response, err error) bool {
+
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	if idempotent(request) {
+		return err != nil || response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError
+	}
+
+	if err != nil {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+
+	return (response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable) &&
+		len(response.Header.Get("Retry-After")) > 0
+}
+
+// idempotent follows net/http, which also takes a request with an
+// Idempotency-Key header as safe to send again. Callers opt in with it.
+func idempotent(request *http.Request) bool {
+
+	switch request.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, ok := request.Header["Idempotency-Key"]
+
+	if !ok {
+		_, ok = request.Header["X-Idempotency-Key"]
+	}
+
+	return ok
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+
+	if len(value) < 1 {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+
+	return 0, false
+}
+
+func rewind(request *http.Request) (*http.Request, error) {
+
+	if request.GetBody == nil {
+		return request, nil
+	}
+
+	body, err := request.GetBody()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not rewind body of %s %s: %w", request.Method, request.URL.Redacted(), err)
+	}
+
+	request = request.Clone(request.Context())
+	request.Body = body
+
+	return request, nil
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func describe(response *http.Response, err error) string {
+
+	if err != nil {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("status code %d", response.StatusCode)
+}
@@ -0,0 +1,279 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testConfig() Config {
+
+	return Config{
+		Timeout:          time.Second,
+		MaxAttempts:      3,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         2 * time.Second,
+		FailureThreshold: 10,
+		OpenDuration:     time.Hour,
+	}
+}
+
+// newTestServer answers with the given status codes in turn, and with 200
+// once they run out.
+func newTestServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+
+	calls := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		call := int(calls.Add(1))
+
+		if call > len(statuses) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if len(retryAfter) > 0 {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+
+		w.WriteHeader(statuses[call-1])
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, calls
+}
+
+func get(t *testing.T, client *http.Client, ctx context.Context, target string) (*http.Response, error) {
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := client.Do(request)
+
+	if err == nil {
+		t.Cleanup(func() { response.Body.Close() })
+	}
+
+	return response, err
+}
+
+func post(t *testing.T, client *http.Client, target string, header http.Header) (*http.Response, error) {
+
+	request, err := http.NewRequest(http.MethodPost, target, strings.NewReader("{}"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, values := range header {
+		request.Header[name] = values
+	}
+
+	response, err := client.Do(request)
+
+	if err == nil {
+		t.Cleanup(func() { response.Body.Close() })
+	}
+
+	return response, err
+}
+
+func TestTransportRetriesServerErrors(t *testing.T) {
+
+	server, calls := newTestServer(t, "", http.StatusServiceUnavailable, http.StatusInternalServerError)
+
+	response, err := get(t, NewTransport(testConfig()).Client(), context.Background(), server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("got %d after %d calls, want 200 after 3", response.StatusCode, calls.Load())
+	}
+}
+
+func TestTransportGivesUpAfterMaxAttempts(t *testing.T) {
+
+	server, calls := newTestServer(t, "", http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+
+	response, err := get(t, NewTransport(testConfig()).Client(), context.Background(), server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusBadGateway || calls.Load() != 3 {
+		t.Fatalf("got %d after %d calls, want 502 after 3", response.StatusCode, calls.Load())
+	}
+}
+
+func TestTransportDoesNotRetryClientErrors(t *testing.T) {
+
+	server, calls := newTestServer(t, "", http.StatusBadRequest)
+
+	response, err := get(t, NewTransport(testConfig()).Client(), context.Background(), server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest || calls.Load() != 1 {
+		t.Fatalf("got %d after %d calls, want 400 after 1", response.StatusCode, calls.Load())
+	}
+}
+
+func TestTransportDoesNotRetryPostsOnServerErrors(t *testing.T) {
+
+	server, calls := newTestServer(t, "", http.StatusInternalServerError)
+
+	response, err := post(t, NewTransport(testConfig()).Client(), server.URL, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError || calls.Load() != 1 {
+		t.Fatalf("got %d after %d calls, want 500 after 1", response.StatusCode, calls.Load())
+	}
+}
+
+func TestTransportRetriesPostsTurnedAwayWithRetryAfter(t *testing.T) {
+
+	server, calls := newTestServer(t, "0", http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	response, err := post(t, NewTransport(testConfig()).Client(), server.URL, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("got %d after %d calls, want 200 after 3", response.StatusCode, calls.Load())
+	}
+}
+
+func TestTransportRetriesPostsWithAnIdempotencyKey(t *testing.T) {
+
+	server, calls := newTestServer(t, "", http.StatusBadGateway)
+
+	response, err := post(t, NewTransport(testConfig()).Client(), server.URL, http.Header{"Idempotency-Key": {"key"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Fatalf("got %d after %d calls, want 200 after 2", response.StatusCode, calls.Load())
+	}
+}
+
+func TestTransportHonoursRetryAfter(t *testing.T) {
+
+	server, calls := newTestServer(t, "1", http.StatusTooManyRequests)
+	start := time.Now()
+
+	response, err := get(t, NewTransport(testConfig()).Client(), context.Background(), server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Fatalf("got %d after %d calls, want 200 after 2", response.StatusCode, calls.Load())
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("retried after %s, before the 1s asked for by Retry-After", elapsed)
+	}
+}
+
+func TestTransportDoesNotWaitLongerThanMaxDelay(t *testing.T) {
+
+	server, calls := newTestServer(t, "120", http.StatusTooManyRequests)
+
+	response, err := get(t, NewTransport(testConfig()).Client(), context.Background(), server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Fatalf("got %d after %d calls, want 429 after 1", response.StatusCode, calls.Load())
+	}
+}
+
+func TestTransportDoesNotRetryWhenContextTimesOut(t *testing.T) {
+
+	calls := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-r.Context().Done()
+	}))
+
+	t.Cleanup(server.Close)
+
+	outboundTransport := NewTransport(testConfig())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := get(t, outboundTransport.Client(), ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if calls.Load() != 1 {
+		t.Fatalf("called the server %d times, want 1", calls.Load())
+	}
+
+	host, _ := url.Parse(server.URL)
+
+	if state := outboundTransport.BreakerStates()[host.Host]; state != BreakerClosed {
+		t.Fatalf("breaker is %s after the caller gave up, want %s", state, BreakerClosed)
+	}
+}
+
+func TestTransportFailsFastWhenBreakerIsOpen(t *testing.T) {
+
+	server, calls := newTestServer(t, "", http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	config := testConfig()
+	config.MaxAttempts = 1
+	config.FailureThreshold = 2
+	client := NewTransport(config).Client()
+
+	for i := 0; i < 2; i++ {
+		if _, err := get(t, client, context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := get(t, client, context.Background(), server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want %v", err, ErrCircuitOpen)
+	}
+
+	if calls.Load() != 2 {
+		t.Fatalf("called the server %d times, want 2", calls.Load())
+	}
+}
+
+func TestTransportTracksHostsBeforeTheyAreCalled(t *testing.T) {
+
+	outboundTransport := NewTransport(testConfig())
+	outboundTransport.Track("weaviate:8080")
+
+	if state := outboundTransport.BreakerStates()["weaviate:8080"]; state != BreakerClosed {
+		t.Fatalf("tracked host is %q, want %s", state, BreakerClosed)
+	}
+}
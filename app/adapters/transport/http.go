@@ -4,67 +4,179 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os"
-	"os/signal"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/metrics"
 	"github.com/utsavgupta/knowledge-hub/app/runners"
+	"github.com/utsavgupta/knowledge-hub/app/tracing"
 	"github.com/utsavgupta/knowledge-hub/app/uc"
 )
 
+// HttpServerConfig sets the timeouts of the app server. On shutdown,
+// readiness starts failing and the server keeps serving for DrainDelay, so
+// that load balancers stop sending it traffic. It then waits up to
+// ShutdownTimeout for in-flight requests before cutting them off.
+type HttpServerConfig struct {
+	Port            int
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+}
+
 type httpRunner struct {
-	port   int
-	router http.Handler
+	config   HttpServerConfig
+	router   http.Handler
+	draining *atomic.Bool
 }
 
 type HttpRunnerDependencies struct {
 	uc.SearchUc
+	uc.StreamSearchUc
 	uc.ListDomainsUc
 	uc.AddDomainUc
 	uc.DeleteDomainUc
+	uc.GetDomainUc
+	uc.UpdateDomainUc
 	uc.ListResourcesUc
 	uc.AddResourceUc
 	uc.DeleteResourceUc
+	uc.ReingestResourceUc
+	uc.GetResourceUc
+	uc.UpdateResourceUc
+	uc.StartConversationUc
+	uc.GetConversationUc
+	uc.SendMessageUc
+	uc.MintApiKeyUc
+	uc.ListApiKeysUc
+	uc.RevokeApiKeyUc
+	uc.ThrottleUc
+	uc.CheckReadinessUc
+	// AuthenticateUc is left unset to serve the API without authentication.
+	uc.AuthenticateUc
+	// Metrics is left unset to serve the API without a /metrics endpoint.
+	Metrics *metrics.Metrics
+}
+
+func NewHttpRunner(config HttpServerConfig, dependencies HttpRunnerDependencies) runners.Runner {
+
+	draining := &atomic.Bool{}
+	api, err := loadOpenApi()
+
+	// The document is embedded, so it can only be broken by a change that
+	// the tests catch.
+	if err != nil {
+		panic(err)
+	}
+
+	return &httpRunner{config, keepResponseController(tracing.Handler(newHandler(dependencies, api, draining))), draining}
+}
+
+// newHandler assigns request ids around the whole router, since requests that
+// no route matches skip the middlewares of the router.
+func newHandler(dependencies HttpRunnerDependencies, api *openApi, draining *atomic.Bool) http.Handler {
+
+	return requestIdMiddleware(newRouter(dependencies, api, draining))
 }
 
-func NewHttpRunner(port int, dependencies HttpRunnerDependencies) runners.Runner {
+func newRouter(dependencies HttpRunnerDependencies, api *openApi, draining *atomic.Bool) *mux.Router {
+
+	root := mux.NewRouter()
+	root.Use(tracing.RouteMiddleware)
+	root.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	root.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+
+	if dependencies.Metrics != nil {
+		root.Use(dependencies.Metrics.Middleware)
+		root.NewRoute().Handler(dependencies.Metrics.Handler()).Path("/metrics").Methods(http.MethodGet)
+	}
 
-	router := mux.NewRouter()
+	root.NewRoute().HandlerFunc(NewHealthHandler()).Path("/healthz").Methods(http.MethodGet)
+	root.NewRoute().HandlerFunc(NewReadinessHandler(dependencies.CheckReadinessUc, draining)).Path("/readyz").Methods(http.MethodGet)
+	root.NewRoute().HandlerFunc(newOpenApiHandler(api)).Path("/openapi.json").Methods(http.MethodGet)
+	root.NewRoute().Handler(newDocsHandler()).PathPrefix(docsPath).Methods(http.MethodGet)
 
+	// The API routes are kept on a subrouter so that probes, scrapes of
+	// /metrics and the documentation are neither authenticated, rate limited
+	// nor validated.
+	router := root.PathPrefix("/").Subrouter()
+
+	if dependencies.AuthenticateUc != nil {
+		router.Use(NewAuthMiddleware(dependencies.AuthenticateUc))
+	}
+
+	router.Use(routeFieldsMiddleware, NewRateLimitMiddleware(dependencies.ThrottleUc), newValidationMiddleware(api))
+
+	router.NewRoute().HandlerFunc(NewStreamSearchHandler(dependencies.StreamSearchUc)).Path("/search/stream").Methods(http.MethodGet)
+	router.NewRoute().HandlerFunc(NewStreamSearchHandler(dependencies.StreamSearchUc)).Path("/search").Methods(http.MethodGet).HeadersRegexp("Accept", "text/event-stream")
 	router.NewRoute().HandlerFunc(NewSearchHandler(dependencies.SearchUc)).Path("/search").Methods(http.MethodGet)
 	router.NewRoute().HandlerFunc(NewListDomainsHandler(dependencies.ListDomainsUc)).Path("/domains").Methods(http.MethodGet)
-	router.NewRoute().HandlerFunc(NewAddDomainHandler(dependencies.AddDomainUc)).Path("/domains").Methods(http.MethodPost)
-	router.NewRoute().HandlerFunc(NewDeleteDomainHandler(dependencies.DeleteDomainUc)).Path("/domains/{domain_id}").Methods(http.MethodDelete)
-	router.NewRoute().HandlerFunc(NewListResourcesHandler(dependencies.ListResourcesUc)).Path("/domains/{domain_id}/resources").Methods(http.MethodGet)
-	router.NewRoute().HandlerFunc(NewAddResourceHandler(dependencies.AddResourceUc)).Path("/domains/{domain_id}/resources").Methods(http.MethodPost)
-	router.NewRoute().HandlerFunc(NewDeleteResourceHandler(dependencies.DeleteResourceUc)).Path("/domains/{domain_id}/resources/{resource_id}").Methods(http.MethodDelete)
-
-	return &httpRunner{port, router}
+	router.NewRoute().HandlerFunc(authorize(isAdmin, NewAddDomainHandler(dependencies.AddDomainUc))).Path("/domains").Methods(http.MethodPost)
+	router.NewRoute().HandlerFunc(authorize(isAdmin, NewDeleteDomainHandler(dependencies.DeleteDomainUc))).Path("/domains/{domain_id}").Methods(http.MethodDelete)
+	router.NewRoute().HandlerFunc(authorize(canReadDomain, NewGetDomainHandler(dependencies.GetDomainUc))).Path("/domains/{domain_id}").Methods(http.MethodGet)
+	router.NewRoute().HandlerFunc(authorize(isAdmin, NewUpdateDomainHandler(dependencies.UpdateDomainUc))).Path("/domains/{domain_id}").Methods(http.MethodPatch)
+	router.NewRoute().HandlerFunc(authorize(canReadDomain, NewListResourcesHandler(dependencies.ListResourcesUc))).Path("/domains/{domain_id}/resources").Methods(http.MethodGet)
+	router.NewRoute().HandlerFunc(authorize(canEditDomain, NewAddResourceHandler(dependencies.AddResourceUc))).Path("/domains/{domain_id}/resources").Methods(http.MethodPost)
+	router.NewRoute().HandlerFunc(authorize(canEditDomain, NewDeleteResourceHandler(dependencies.DeleteResourceUc))).Path("/domains/{domain_id}/resources/{resource_id}").Methods(http.MethodDelete)
+	router.NewRoute().HandlerFunc(authorize(canReadDomain, NewGetResourceHandler(dependencies.GetResourceUc))).Path("/domains/{domain_id}/resources/{resource_id}").Methods(http.MethodGet)
+	router.NewRoute().HandlerFunc(authorize(canEditDomain, NewUpdateResourceHandler(dependencies.UpdateResourceUc))).Path("/domains/{domain_id}/resources/{resource_id}").Methods(http.MethodPatch)
+	router.NewRoute().HandlerFunc(authorize(canEditDomain, NewReingestResourceHandler(dependencies.ReingestResourceUc))).Path("/domains/{domain_id}/resources/{resource_id}/reingest").Methods(http.MethodPost)
+	router.NewRoute().HandlerFunc(authorize(canReadDomain, NewStartConversationHandler(dependencies.StartConversationUc))).Path("/domains/{domain_id}/conversations").Methods(http.MethodPost)
+	router.NewRoute().HandlerFunc(NewGetConversationHandler(dependencies.GetConversationUc)).Path("/conversations/{conversation_id}").Methods(http.MethodGet)
+	router.NewRoute().HandlerFunc(NewSendMessageHandler(dependencies.SendMessageUc)).Path("/conversations/{conversation_id}/messages").Methods(http.MethodPost)
+	router.NewRoute().HandlerFunc(authorize(isAdmin, NewListApiKeysHandler(dependencies.ListApiKeysUc))).Path("/api-keys").Methods(http.MethodGet)
+	router.NewRoute().HandlerFunc(authorize(isAdmin, NewMintApiKeyHandler(dependencies.MintApiKeyUc))).Path("/api-keys").Methods(http.MethodPost)
+	router.NewRoute().HandlerFunc(authorize(isAdmin, NewRevokeApiKeyHandler(dependencies.RevokeApiKeyUc))).Path("/api-keys/{key_id}").Methods(http.MethodDelete)
+	router.NewRoute().HandlerFunc(authorize(isAdmin, NewGetLogLevelHandler())).Path("/admin/log-level").Methods(http.MethodGet)
+	router.NewRoute().HandlerFunc(authorize(isAdmin, NewSetLogLevelHandler())).Path("/admin/log-level").Methods(http.MethodPut)
+
+	return root
 }
 
-func (runner httpRunner) Run() error {
+func (runner httpRunner) Run(ctx context.Context) error {
+
+	logger.Instance().Info(ctx, fmt.Sprintf("Starting app server on port %d", runner.config.Port))
 
-	logger.Instance().Info(context.Background(), fmt.Sprintf("Starting app server on port %d", runner.port))
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", runner.config.Port),
+		Handler:      runner.router,
+		ReadTimeout:  runner.config.ReadTimeout,
+		WriteTimeout: runner.config.WriteTimeout,
+		IdleTimeout:  runner.config.IdleTimeout,
+	}
 
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 
 	go func(c chan error) {
 
-		if err := http.ListenAndServe(fmt.Sprintf(":%d", runner.port), runner.router); err != nil {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			c <- err
 		}
 	}(errChan)
 
-	intChannel := make(chan os.Signal)
-	signal.Notify(intChannel, os.Interrupt)
-
 	select {
 	case err := <-errChan:
 		logger.Instance().Error(context.Background(), fmt.Sprintf("Exiting app server: %s", err.Error()))
 		return err
-	case <-intChannel:
-		logger.Instance().Info(context.Background(), fmt.Sprintf("Stopping app server"))
+	case <-ctx.Done():
+		logger.Instance().Info(context.Background(), fmt.Sprintf("Draining app server for %s", runner.config.DrainDelay))
+	}
+
+	runner.draining.Store(true)
+	time.Sleep(runner.config.DrainDelay)
+
+	logger.Instance().Info(context.Background(), "Stopping app server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), runner.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return fmt.Errorf("could not finish in-flight requests within %s: %w", runner.config.ShutdownTimeout, err)
 	}
 
 	return nil
@@ -2,10 +2,13 @@ package transport
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/utsavgupta/knowledge-hub/app/entities"
@@ -13,39 +16,96 @@ import (
 	"github.com/utsavgupta/knowledge-hub/app/uc"
 )
 
-type apiError struct {
-	Code int    `json:"code"`
-	Err  string `json:"error"`
+type messageRequest struct {
+	Question string `json:"question"`
+}
+
+const mergePatchMediaType = "application/merge-patch+json"
+
+type logLevelBody struct {
+	Level string `json:"level"`
 }
 
 func NewSearchHandler(searchUc uc.SearchUc) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		domainId := r.URL.Query().Get("domain_id")
+		query, err := parseSearchQuery(r)
 
-		if len(domainId) < 1 {
-			handleClientError(w, r, fmt.Errorf("domain_id cannot be empty."))
+		if err != nil {
+			handleClientError(w, r, err)
 			return
 		}
 
-		question := r.URL.Query().Get("question")
+		answer, err := searchUc(r.Context(), *query)
 
-		if len(question) < 1 {
-			handleClientError(w, r, fmt.Errorf("question cannot be empty."))
+		if err != nil {
+			handleError(w, r, err)
 			return
 		}
 
-		query := entities.Query{Question: question, DomainId: domainId}
+		sendResponse(w, r, http.StatusOK, answer)
+	}
+}
+
+func NewStreamSearchHandler(streamSearchUc uc.StreamSearchUc) http.HandlerFunc {
 
-		answer, err := searchUc(r.Context(), query)
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		query, err := parseSearchQuery(r)
 
 		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+
+		if !ok {
+			handleServerError(w, r, fmt.Errorf("streaming is not supported by the response writer"))
+			return
+		}
+
+		// Streamed answers may take longer than the write timeout of the server,
+		// and are bounded by the timeout of the LLM instead.
+		if err := clearWriteDeadline(r); err != nil {
+			logger.Instance().Warn(r.Context(), fmt.Sprintf("could not lift write deadline of stream: %v", err))
+		}
+
+		streaming := false
+
+		_, err = streamSearchUc(r.Context(), *query, func(event entities.ResponseEvent) error {
+
+			if !streaming {
+				w.Header().Add("Content-Type", "text/event-stream")
+				w.Header().Add("Cache-Control", "no-cache")
+				w.Header().Add("Connection", "keep-alive")
+				w.WriteHeader(http.StatusOK)
+				streaming = true
+			}
+
+			if err := sendEvent(w, event.Type, eventPayload(event)); err != nil {
+				return err
+			}
+
+			flusher.Flush()
+			return nil
+		})
+
+		if err == nil {
+			return
+		}
+
+		if !streaming {
 			handleError(w, r, err)
 			return
 		}
 
-		sendResponse(w, r, http.StatusOK, answer)
+		if err := sendEvent(w, "error", newProblem(r, err)); err != nil {
+			logger.Instance().Error(r.Context(), err.Error())
+		}
+
+		flusher.Flush()
 	}
 }
 
@@ -53,14 +113,21 @@ func NewListDomainsHandler(listDomainsUc uc.ListDomainsUc) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		domains, err := listDomainsUc(r.Context())
+		page, err := parsePageRequest(r)
+
+		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		domains, err := listDomainsUc(r.Context(), entities.DomainFilter{Search: r.URL.Query().Get("q")}, *page)
 
 		if err != nil {
 			handleError(w, r, err)
 			return
 		}
 
-		sendResponse(w, r, http.StatusOK, domains)
+		sendResponse(w, r, http.StatusOK, *domains)
 	}
 }
 
@@ -101,7 +168,7 @@ func NewDeleteDomainHandler(deleteDomainUc uc.DeleteDomainUc) http.HandlerFunc {
 		}
 
 		if err := deleteDomainUc(r.Context(), domainId); err != nil {
-			handleServerError(w, r, fmt.Errorf("internal server error"))
+			handleError(w, r, err)
 			return
 		}
 
@@ -109,6 +176,68 @@ func NewDeleteDomainHandler(deleteDomainUc uc.DeleteDomainUc) http.HandlerFunc {
 	}
 }
 
+func NewGetDomainHandler(getDomainUc uc.GetDomainUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		domainId, ok := vars["domain_id"]
+
+		if !ok {
+			handleClientError(w, r, fmt.Errorf("domain id not provided"))
+			return
+		}
+
+		domain, err := getDomainUc(r.Context(), domainId)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		w.Header().Set("ETag", formatETag(domain.Version()))
+		sendResponse(w, r, http.StatusOK, *domain)
+	}
+}
+
+func NewUpdateDomainHandler(updateDomainUc uc.UpdateDomainUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		vars := mux.Vars(r)
+		domainId, ok := vars["domain_id"]
+
+		if !ok {
+			handleClientError(w, r, fmt.Errorf("domain id not provided"))
+			return
+		}
+
+		patch := entities.DomainPatch{}
+
+		if err := decodeMergePatch(r, &patch); err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		version, err := parseIfMatch(r)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		domain, err := updateDomainUc(r.Context(), domainId, patch, version)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		w.Header().Set("ETag", formatETag(domain.Version()))
+		sendResponse(w, r, http.StatusOK, *domain)
+	}
+}
+
 func NewListResourcesHandler(listResourcesUc uc.ListResourcesUc) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -121,14 +250,28 @@ func NewListResourcesHandler(listResourcesUc uc.ListResourcesUc) http.HandlerFun
 			return
 		}
 
-		resources, err := listResourcesUc(r.Context(), domainId)
+		page, err := parsePageRequest(r)
+
+		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		filter, err := parseResourceFilter(r, domainId)
+
+		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		resources, err := listResourcesUc(r.Context(), *filter, *page)
 
 		if err != nil {
 			handleError(w, r, err)
 			return
 		}
 
-		sendResponse(w, r, http.StatusOK, resources)
+		sendResponse(w, r, http.StatusOK, *resources)
 	}
 }
 
@@ -169,6 +312,118 @@ func NewAddResourceHandler(addResourceUc uc.AddResourceUc) http.HandlerFunc {
 
 func NewDeleteResourceHandler(deleteResourceUc uc.DeleteResourceUc) http.HandlerFunc {
 
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		domainId, resourceId, err := parseResourcePath(r)
+
+		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		if err := deleteResourceUc(r.Context(), domainId, resourceId); err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		sendResponse(w, r, http.StatusOK, "")
+	}
+}
+
+func NewReingestResourceHandler(reingestResourceUc uc.ReingestResourceUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		domainId, resourceId, err := parseResourcePath(r)
+
+		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		force := false
+
+		if v := r.URL.Query().Get("force"); len(v) > 0 {
+
+			if force, err = strconv.ParseBool(v); err != nil {
+				handleClientError(w, r, fmt.Errorf("force should be a boolean"))
+				return
+			}
+		}
+
+		resource, err := reingestResourceUc(r.Context(), domainId, resourceId, force)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		sendResponse(w, r, http.StatusAccepted, *resource)
+	}
+}
+
+func NewGetResourceHandler(getResourceUc uc.GetResourceUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		domainId, resourceId, err := parseResourcePath(r)
+
+		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		resource, err := getResourceUc(r.Context(), domainId, resourceId)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		w.Header().Set("ETag", formatETag(resource.Version()))
+		sendResponse(w, r, http.StatusOK, *resource)
+	}
+}
+
+func NewUpdateResourceHandler(updateResourceUc uc.UpdateResourceUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		domainId, resourceId, err := parseResourcePath(r)
+
+		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		patch := entities.ResourcePatch{}
+
+		if err = decodeMergePatch(r, &patch); err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		version, err := parseIfMatch(r)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		resource, err := updateResourceUc(r.Context(), domainId, resourceId, patch, version)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		w.Header().Set("ETag", formatETag(resource.Version()))
+		sendResponse(w, r, http.StatusOK, *resource)
+	}
+}
+
+func NewStartConversationHandler(startConversationUc uc.StartConversationUc) http.HandlerFunc {
+
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		vars := mux.Vars(r)
@@ -179,22 +434,124 @@ func NewDeleteResourceHandler(deleteResourceUc uc.DeleteResourceUc) http.Handler
 			return
 		}
 
-		resourceId, ok := vars["resource_id"]
+		conversation, err := startConversationUc(r.Context(), domainId)
 
-		if !ok {
-			handleClientError(w, r, fmt.Errorf("resource id not provided"))
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		sendResponse(w, r, http.StatusCreated, *conversation)
+	}
+}
+
+func NewGetConversationHandler(getConversationUc uc.GetConversationUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		conversationId, err := parseConversationPath(r)
+
+		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		conversation, err := getConversationUc(r.Context(), conversationId)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		sendResponse(w, r, http.StatusOK, *conversation)
+	}
+}
+
+func NewSendMessageHandler(sendMessageUc uc.SendMessageUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		conversationId, err := parseConversationPath(r)
+
+		if err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		body := messageRequest{}
+
+		defer r.Body.Close()
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.Instance().Debug(r.Context(), err.Error())
+			handleClientError(w, r, fmt.Errorf("invalid message body. please check documentation."))
+			return
+		}
+
+		message, err := sendMessageUc(r.Context(), conversationId, body.Question)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		sendResponse(w, r, http.StatusCreated, *message)
+	}
+}
+
+func NewMintApiKeyHandler(mintApiKeyUc uc.MintApiKeyUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		apiKey := &entities.ApiKey{}
+
+		defer r.Body.Close()
+
+		if err := json.NewDecoder(r.Body).Decode(apiKey); err != nil {
+			logger.Instance().Debug(r.Context(), err.Error())
+			handleClientError(w, r, fmt.Errorf("invalid message body. please check documentation."))
+			return
+		}
+
+		apiKey, err := mintApiKeyUc(r.Context(), *apiKey)
+
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		sendResponse(w, r, http.StatusCreated, *apiKey)
+	}
+}
+
+func NewListApiKeysHandler(listApiKeysUc uc.ListApiKeysUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		apiKeys, err := listApiKeysUc(r.Context())
+
+		if err != nil {
+			handleError(w, r, err)
 			return
 		}
 
-		resourceIdInt, err := strconv.Atoi(resourceId)
+		sendResponse(w, r, http.StatusOK, apiKeys)
+	}
+}
+
+func NewRevokeApiKeyHandler(revokeApiKeyUc uc.RevokeApiKeyUc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		keyId, err := strconv.Atoi(mux.Vars(r)["key_id"])
 
 		if err != nil {
-			handleClientError(w, r, fmt.Errorf("resource id should be an integer"))
+			handleClientError(w, r, fmt.Errorf("key id should be an integer"))
 			return
 		}
 
-		if err := deleteResourceUc(r.Context(), domainId, resourceIdInt); err != nil {
-			handleServerError(w, r, fmt.Errorf("internal server error"))
+		if err := revokeApiKeyUc(r.Context(), keyId); err != nil {
+			handleError(w, r, err)
 			return
 		}
 
@@ -202,44 +559,315 @@ func NewDeleteResourceHandler(deleteResourceUc uc.DeleteResourceUc) http.Handler
 	}
 }
 
-func handleError(w http.ResponseWriter, r *http.Request, err error) {
+// NewHealthHandler reports that the process is alive without checking any
+// dependency.
+func NewHealthHandler() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
 
-	if errors.Is(err, uc.ValidationError) {
-		handleClientError(w, r, err)
-		return
+		sendResponse(w, r, http.StatusOK, map[string]string{"status": "ok"})
 	}
+}
+
+// NewReadinessHandler fails as soon as the server starts draining, without
+// probing dependencies, so that no new traffic is sent to it.
+func NewReadinessHandler(checkReadinessUc uc.CheckReadinessUc, draining *atomic.Bool) http.HandlerFunc {
 
-	handleServerError(w, r, err)
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if draining.Load() {
+			sendResponse(w, r, http.StatusServiceUnavailable, entities.Readiness{Draining: true, Dependencies: []entities.DependencyHealth{}})
+			return
+		}
+
+		readiness := checkReadinessUc(r.Context())
+		status := http.StatusOK
+
+		if !readiness.Ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		sendResponse(w, r, status, readiness)
+	}
 }
 
-func sendResponse(w http.ResponseWriter, r *http.Request, status int, body any) {
+func NewGetLogLevelHandler() http.HandlerFunc {
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(status)
+	return func(w http.ResponseWriter, r *http.Request) {
 
-	if err := json.NewEncoder(w).Encode(body); err != nil {
-		logger.Instance().Error(r.Context(), err.Error())
+		sendResponse(w, r, http.StatusOK, logLevelBody{logger.Instance().Level()})
 	}
 }
 
-func handleClientError(w http.ResponseWriter, r *http.Request, err error) {
+// NewSetLogLevelHandler changes the log level of this process until it is
+// restarted or changed again. Other replicas keep their level.
+func NewSetLogLevelHandler() http.HandlerFunc {
 
-	body := apiError{http.StatusBadRequest, err.Error()}
+	return func(w http.ResponseWriter, r *http.Request) {
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadRequest)
+		body := logLevelBody{}
 
-	if err := json.NewEncoder(w).Encode(body); err != nil {
-		logger.Instance().Error(r.Context(), err.Error())
+		defer r.Body.Close()
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.Instance().Debug(r.Context(), err.Error())
+			handleClientError(w, r, fmt.Errorf("invalid log level body. please check documentation."))
+			return
+		}
+
+		previous := logger.Instance().Level()
+
+		if err := logger.Instance().SetLevel(body.Level); err != nil {
+			handleClientError(w, r, err)
+			return
+		}
+
+		logger.Instance().Warn(r.Context(), fmt.Sprintf("Changed log level from %s to %s", previous, logger.Instance().Level()))
+
+		sendResponse(w, r, http.StatusOK, logLevelBody{logger.Instance().Level()})
+	}
+}
+
+func parseConversationPath(r *http.Request) (int, error) {
+
+	conversationId, ok := mux.Vars(r)["conversation_id"]
+
+	if !ok {
+		return -1, fmt.Errorf("conversation id not provided")
+	}
+
+	conversationIdInt, err := strconv.Atoi(conversationId)
+
+	if err != nil {
+		return -1, fmt.Errorf("conversation id should be an integer")
 	}
+
+	return conversationIdInt, nil
+}
+
+func parseResourcePath(r *http.Request) (string, int, error) {
+
+	vars := mux.Vars(r)
+	domainId, ok := vars["domain_id"]
+
+	if !ok {
+		return "", -1, fmt.Errorf("domain id not provided")
+	}
+
+	resourceId, ok := vars["resource_id"]
+
+	if !ok {
+		return "", -1, fmt.Errorf("resource id not provided")
+	}
+
+	resourceIdInt, err := strconv.Atoi(resourceId)
+
+	if err != nil {
+		return "", -1, fmt.Errorf("resource id should be an integer")
+	}
+
+	return domainId, resourceIdInt, nil
+}
+
+func parsePageRequest(r *http.Request) (*entities.PageRequest, error) {
+
+	values := r.URL.Query()
+	page := &entities.PageRequest{SortBy: values.Get("sort")}
+
+	if v := values.Get("limit"); len(v) > 0 {
+
+		limit, err := strconv.Atoi(v)
+
+		if err != nil {
+			return nil, fmt.Errorf("limit should be an integer")
+		}
+
+		page.Limit = limit
+	}
+
+	if v := values.Get("cursor"); len(v) > 0 {
+
+		cursor, err := entities.DecodeCursor(v)
+
+		if err != nil {
+			return nil, fmt.Errorf("cursor should be the nextCursor of a previous page")
+		}
+
+		page.Cursor = cursor
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		page.Descending = true
+	default:
+		return nil, fmt.Errorf("order should be asc or desc")
+	}
+
+	return page, nil
+}
+
+func parseResourceFilter(r *http.Request, domainId string) (*entities.ResourceFilter, error) {
+
+	values := r.URL.Query()
+	filter := &entities.ResourceFilter{DomainId: domainId, Search: values.Get("q")}
+
+	for _, v := range values["status"] {
+		for _, status := range strings.Split(v, ",") {
+			if status = strings.ToUpper(strings.TrimSpace(status)); len(status) > 0 {
+				filter.Statuses = append(filter.Statuses, status)
+			}
+		}
+	}
+
+	times := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"createdAfter", &filter.CreatedAfter},
+		{"createdBefore", &filter.CreatedBefore},
+		{"ingestedAfter", &filter.IngestedAfter},
+		{"ingestedBefore", &filter.IngestedBefore},
+	}
+
+	for _, t := range times {
+
+		v := values.Get(t.name)
+
+		if len(v) < 1 {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, v)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s should be an RFC 3339 timestamp", t.name)
+		}
+
+		*t.target = &parsed
+	}
+
+	return filter, nil
+}
+
+// decodeMergePatch reads a JSON merge patch, rejecting fields that cannot be
+// changed.
+func decodeMergePatch(r *http.Request, patch any) error {
+
+	defer r.Body.Close()
+
+	mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+
+	if mediaType = strings.TrimSpace(mediaType); len(mediaType) > 0 && mediaType != mergePatchMediaType && mediaType != "application/json" {
+		return fmt.Errorf("the body should be a %s document", mergePatchMediaType)
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(patch); err != nil {
+		logger.Instance().Debug(r.Context(), err.Error())
+		return fmt.Errorf("invalid patch body. only the documented fields can be changed.")
+	}
+
+	return nil
+}
+
+// formatETag derives the entity tag of an entity from the time it was last
+// changed.
+func formatETag(version time.Time) string {
+
+	return strconv.Quote(strconv.FormatInt(version.UnixMicro(), 10))
 }
 
-func handleServerError(w http.ResponseWriter, r *http.Request, err error) {
+// parseIfMatch returns the version an update is conditional on, or nil when
+// the request is unconditional.
+func parseIfMatch(r *http.Request) (*time.Time, error) {
+
+	etag := strings.TrimSpace(r.Header.Get("If-Match"))
+
+	if len(etag) < 1 || etag == "*" {
+		return nil, nil
+	}
+
+	micros, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(etag, "W/"), `"`), 10, 64)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: If-Match does not hold an entity tag of this api", uc.PreconditionFailedError)
+	}
 
-	body := apiError{http.StatusInternalServerError, "Internal Server Error"}
+	version := time.UnixMicro(micros)
+
+	return &version, nil
+}
+
+func parseSearchQuery(r *http.Request) (*entities.Query, error) {
+
+	domainIds := make([]string, 0)
+
+	// Domains can be passed as repeated domain_id parameters or as a comma separated list.
+	for _, v := range r.URL.Query()["domain_id"] {
+		for _, domainId := range strings.Split(v, ",") {
+			if domainId = strings.TrimSpace(domainId); len(domainId) > 0 {
+				domainIds = append(domainIds, domainId)
+			}
+		}
+	}
+
+	if len(domainIds) < 1 {
+		return nil, fmt.Errorf("domain_id cannot be empty.")
+	}
+
+	if len(domainIds) > 1 && slices.Contains(domainIds, entities.AllDomains) {
+		return nil, fmt.Errorf("domain_id %s cannot be combined with other domains.", entities.AllDomains)
+	}
+
+	question := r.URL.Query().Get("question")
+
+	if len(question) < 1 {
+		return nil, fmt.Errorf("question cannot be empty.")
+	}
+
+	return &entities.Query{Question: question, DomainIds: domainIds}, nil
+}
+
+// eventPayload is the data of an event, which is its bare payload rather than
+// the whole event, as the name of the event already tells its type.
+func eventPayload(event entities.ResponseEvent) any {
+
+	switch event.Type {
+	case entities.ResponseEventConcepts:
+		return event.Concepts
+	case entities.ResponseEventSources:
+		return event.Sources
+	case entities.ResponseEventToken:
+		return event.Token
+	case entities.ResponseEventDone:
+		return event.Response
+	}
+
+	return event
+}
+
+func sendEvent(w http.ResponseWriter, name string, body any) error {
+
+	data, err := json.Marshal(body)
+
+	if err != nil {
+		return fmt.Errorf("could not marshall event %s: %w", name, err)
+	}
+
+	if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return fmt.Errorf("could not write event %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func sendResponse(w http.ResponseWriter, r *http.Request, status int, body any) {
 
 	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeader(status)
 
 	if err := json.NewEncoder(w).Encode(body); err != nil {
 		logger.Instance().Error(r.Context(), err.Error())
@@ -0,0 +1,249 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/uc"
+)
+
+const (
+	apiKeyHeader    = "X-API-Key"
+	requestIdHeader = "X-Request-Id"
+	maxRequestIdLen = 128
+)
+
+// routeLogFields maps path variables to the log fields they are reported as.
+var routeLogFields = map[string]string{
+	"domain_id":       logger.FieldDomainId,
+	"resource_id":     logger.FieldResourceId,
+	"conversation_id": logger.FieldConversationId,
+}
+
+type identityCheck func(entities.Identity, *http.Request) bool
+
+// NewAuthMiddleware authenticates the api key sent as a Bearer token or in the
+// X-API-Key header and places the identity of the caller in the request context.
+func NewAuthMiddleware(authenticateUc uc.AuthenticateUc) mux.MiddlewareFunc {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			identity, err := authenticateUc(r.Context(), extractApiKey(r))
+
+			if err != nil {
+				handleError(w, r, err)
+				return
+			}
+
+			ctx := entities.WithIdentity(r.Context(), *identity)
+			ctx = logger.WithFields(ctx, logger.Fields{logger.FieldCaller: identity.Name, "callerKeyId": identity.KeyId})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authorize only lets callers that pass the check through. Requests without
+// an identity are let through as well, as they can only reach the handler
+// when authentication is disabled.
+func authorize(check identityCheck, next http.HandlerFunc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if identity, ok := entities.IdentityFromContext(r.Context()); ok && !check(identity, r) {
+			handleError(w, r, fmt.Errorf("%w: api key %s cannot perform this operation", uc.ForbiddenError, identity.Name))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func isAdmin(identity entities.Identity, r *http.Request) bool {
+
+	return identity.IsAdmin()
+}
+
+func canReadDomain(identity entities.Identity, r *http.Request) bool {
+
+	return identity.CanRead(mux.Vars(r)["domain_id"])
+}
+
+func canEditDomain(identity entities.Identity, r *http.Request) bool {
+
+	return identity.CanEdit(mux.Vars(r)["domain_id"])
+}
+
+// NewRateLimitMiddleware throttles callers by api key, or by address when
+// authentication is disabled, and the domains their requests are about. It
+// runs after authentication, and the throttle use case leaves out the domains
+// the caller cannot read.
+func NewRateLimitMiddleware(throttleUc uc.ThrottleUc) mux.MiddlewareFunc {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			if err := throttleUc(r.Context(), rateLimitClient(r), rateLimitDomains(r)); err != nil {
+				handleError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func rateLimitClient(r *http.Request) string {
+
+	if identity, ok := entities.IdentityFromContext(r.Context()); ok {
+		return fmt.Sprintf("key:%d:%s", identity.KeyId, identity.Name)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+func rateLimitDomains(r *http.Request) []string {
+
+	domainIds := make([]string, 0)
+
+	if domainId, ok := mux.Vars(r)["domain_id"]; ok {
+		domainIds = append(domainIds, domainId)
+	}
+
+	for _, v := range r.URL.Query()["domain_id"] {
+		for _, domainId := range strings.Split(v, ",") {
+			if domainId = strings.TrimSpace(domainId); len(domainId) > 0 && domainId != entities.AllDomains && !slices.Contains(domainIds, domainId) {
+				domainIds = append(domainIds, domainId)
+			}
+		}
+	}
+
+	return domainIds
+}
+
+func extractApiKey(r *http.Request) string {
+
+	if key := r.Header.Get(apiKeyHeader); len(key) > 0 {
+		return key
+	}
+
+	scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(key)
+	}
+
+	return ""
+}
+
+// requestIdMiddleware tags the log entries of a request with the id sent in
+// X-Request-Id, or a new one when it is missing or malformed, and echoes it
+// back in the response.
+func requestIdMiddleware(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		requestId := r.Header.Get(requestIdHeader)
+
+		if !validRequestId(requestId) {
+			requestId = newRequestId()
+		}
+
+		w.Header().Set(requestIdHeader, requestId)
+
+		ctx := logger.WithFields(r.Context(), logger.Fields{logger.FieldRequestId: requestId})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestId(requestId string) bool {
+
+	if len(requestId) < 1 || len(requestId) > maxRequestIdLen {
+		return false
+	}
+
+	for _, c := range requestId {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestId() string {
+
+	b := make([]byte, 16)
+
+	// crypto/rand does not fail on the platforms the app runs on.
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// routeFieldsMiddleware tags the log entries of a request with the domain,
+// resource and conversation in its path.
+func routeFieldsMiddleware(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		fields := logger.Fields{}
+
+		for name, value := range mux.Vars(r) {
+			if field, ok := routeLogFields[name]; ok {
+				fields[field] = value
+			}
+		}
+
+		if len(fields) > 0 {
+			r = r.WithContext(logger.WithFields(r.Context(), fields))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type responseControllerKey struct{}
+
+// keepResponseController remembers the controller of the response written by
+// the server, since the writers wrapping it for tracing and metrics cannot be
+// unwrapped to reach it.
+func keepResponseController(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		ctx := context.WithValue(r.Context(), responseControllerKey{}, http.NewResponseController(w))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clearWriteDeadline lifts the write timeout of the server for a response.
+func clearWriteDeadline(r *http.Request) error {
+
+	controller, ok := r.Context().Value(responseControllerKey{}).(*http.ResponseController)
+
+	if !ok {
+		return fmt.Errorf("response controller is not available")
+	}
+
+	return controller.SetWriteDeadline(time.Time{})
+}
@@ -0,0 +1,212 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/uc"
+)
+
+const (
+	problemMediaType  = "application/problem+json"
+	problemTypePrefix = "urn:knowledge-hub:problem:"
+)
+
+// problemKind is a class of errors clients can branch on by its code, which
+// does not change between releases.
+type problemKind struct {
+	status int
+	code   string
+	title  string
+}
+
+var (
+	problemInvalidRequest      = problemKind{http.StatusBadRequest, "invalid_request", "The request is malformed"}
+	problemValidationFailed    = problemKind{http.StatusBadRequest, "validation_failed", "The request is invalid"}
+	problemUnauthenticated     = problemKind{http.StatusUnauthorized, "unauthenticated", "A valid api key is required"}
+	problemForbidden           = problemKind{http.StatusForbidden, "forbidden", "The api key is not allowed to perform this operation"}
+	problemNotFound            = problemKind{http.StatusNotFound, "not_found", "The resource does not exist"}
+	problemMethodNotAllowed    = problemKind{http.StatusMethodNotAllowed, "method_not_allowed", "The method is not allowed on this path"}
+	problemConflict            = problemKind{http.StatusConflict, "conflict", "The request conflicts with the current state"}
+	problemPreconditionFailed  = problemKind{http.StatusPreconditionFailed, "precondition_failed", "The resource has changed"}
+	problemRateLimited         = problemKind{http.StatusTooManyRequests, "rate_limited", "Too many requests"}
+	problemBudgetExceeded      = problemKind{http.StatusTooManyRequests, "budget_exceeded", "The token budget is used up"}
+	problemInternal            = problemKind{http.StatusInternalServerError, "internal_error", "Internal Server Error"}
+	problemUpstreamUnavailable = problemKind{http.StatusServiceUnavailable, "upstream_unavailable", "A service the answer depends on is unavailable"}
+	problemUpstreamRateLimited = problemKind{http.StatusServiceUnavailable, "upstream_rate_limited", "A service the answer depends on refused the call because of its rate limit or quota"}
+)
+
+// problem is an RFC 7807 problem details document.
+type problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	Code      string         `json:"code"`
+	RequestId string         `json:"requestId,omitempty"`
+	Errors    []fieldProblem `json:"errors,omitempty"`
+}
+
+type fieldProblem struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func classifyError(err error) problemKind {
+
+	var limitErr *uc.LimitError
+
+	switch {
+	case errors.As(err, &limitErr) && limitErr.Budget:
+		return problemBudgetExceeded
+	case errors.Is(err, uc.LimitExceededError):
+		return problemRateLimited
+	case errors.Is(err, uc.ValidationError):
+		return problemValidationFailed
+	case errors.Is(err, uc.UnauthenticatedError):
+		return problemUnauthenticated
+	case errors.Is(err, uc.ForbiddenError):
+		return problemForbidden
+	case errors.Is(err, uc.NotFoundError):
+		return problemNotFound
+	case errors.Is(err, uc.ConflictError):
+		return problemConflict
+	case errors.Is(err, uc.PreconditionFailedError):
+		return problemPreconditionFailed
+	case errors.Is(err, uc.UpstreamRateLimitedError):
+		return problemUpstreamRateLimited
+	case errors.Is(err, uc.UnavailableError):
+		return problemUpstreamUnavailable
+	default:
+		return problemInternal
+	}
+}
+
+func newProblem(r *http.Request, err error) problem {
+
+	return newProblemOfKind(r, classifyError(err), err)
+}
+
+// newProblemOfKind describes err as a problem of the given kind. The details
+// of internal errors are left out.
+func newProblemOfKind(r *http.Request, kind problemKind, err error) problem {
+
+	p := problem{
+		Type:     problemTypePrefix + kind.code,
+		Title:    kind.title,
+		Status:   kind.status,
+		Instance: r.URL.Path,
+		Code:     kind.code,
+	}
+
+	if requestId, ok := logger.FieldsFromContext(r.Context())[logger.FieldRequestId].(string); ok {
+		p.RequestId = requestId
+	}
+
+	if kind == problemInternal || err == nil {
+		return p
+	}
+
+	p.Errors = fieldProblems(err)
+
+	if len(p.Errors) > 0 {
+
+		reasons := make([]string, 0, len(p.Errors))
+
+		for _, fieldErr := range p.Errors {
+			reasons = append(reasons, fieldErr.Reason)
+		}
+
+		p.Detail = strings.Join(reasons, "; ")
+	} else {
+		p.Detail = err.Error()
+	}
+
+	return p
+}
+
+// fieldProblems lists the invalid fields reported by err and the errors it
+// joins.
+func fieldProblems(err error) []fieldProblem {
+
+	var fieldErr *uc.FieldError
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+
+		problems := make([]fieldProblem, 0)
+
+		for _, e := range joined.Unwrap() {
+			problems = append(problems, fieldProblems(e)...)
+		}
+
+		return problems
+	}
+
+	if errors.As(err, &fieldErr) {
+		return []fieldProblem{{fieldErr.Field, fieldErr.Reason}}
+	}
+
+	return nil
+}
+
+func handleError(w http.ResponseWriter, r *http.Request, err error) {
+
+	var limitErr *uc.LimitError
+
+	if errors.As(err, &limitErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+	}
+
+	p := newProblem(r, err)
+
+	if p.Status >= http.StatusInternalServerError {
+		logger.Instance().Error(r.Context(), fmt.Sprintf("Answering %d: %s", p.Status, err.Error()))
+	}
+
+	sendProblem(w, r, p)
+}
+
+// handleClientError answers requests that could not be parsed, or that use
+// cases found invalid.
+func handleClientError(w http.ResponseWriter, r *http.Request, err error) {
+
+	if errors.Is(err, uc.ValidationError) {
+		handleError(w, r, err)
+		return
+	}
+
+	sendProblem(w, r, newProblemOfKind(r, problemInvalidRequest, err))
+}
+
+func handleServerError(w http.ResponseWriter, r *http.Request, err error) {
+
+	logger.Instance().Error(r.Context(), err.Error())
+	sendProblem(w, r, newProblemOfKind(r, problemInternal, err))
+}
+
+func sendProblem(w http.ResponseWriter, r *http.Request, p problem) {
+
+	w.Header().Set("Content-Type", problemMediaType)
+	w.WriteHeader(p.Status)
+
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logger.Instance().Error(r.Context(), err.Error())
+	}
+}
+
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+
+	sendProblem(w, r, newProblemOfKind(r, problemNotFound, fmt.Errorf("no route matches %s", r.URL.Path)))
+}
+
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+
+	sendProblem(w, r, newProblemOfKind(r, problemMethodNotAllowed, fmt.Errorf("%s is not allowed on %s", r.Method, r.URL.Path)))
+}
@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/utsavgupta/knowledge-hub/app/metrics"
+)
+
+func TestUnroutedProblemsCarryRequestId(t *testing.T) {
+
+	api, err := loadOpenApi()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := newHandler(HttpRunnerDependencies{Metrics: metrics.NewMetrics()}, api, &atomic.Bool{})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		code   string
+	}{
+		{"no route", http.MethodGet, "/nowhere", http.StatusNotFound, problemNotFound.code},
+		{"wrong method", http.MethodPost, "/healthz", http.StatusMethodNotAllowed, problemMethodNotAllowed.code},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			request := httptest.NewRequest(test.method, test.target, nil)
+			request.Header.Set(requestIdHeader, "req-42")
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			var p problem
+
+			if err := json.NewDecoder(recorder.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+
+			if recorder.Code != test.status || p.Code != test.code {
+				t.Fatalf("got %d %s, want %d %s", recorder.Code, p.Code, test.status, test.code)
+			}
+
+			if p.RequestId != "req-42" || recorder.Header().Get(requestIdHeader) != "req-42" {
+				t.Fatalf("got request id %q in the problem and %q in the header, want req-42 in both", p.RequestId, recorder.Header().Get(requestIdHeader))
+			}
+		})
+	}
+}
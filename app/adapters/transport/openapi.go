@@ -0,0 +1,223 @@
+package transport
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gorilla/mux"
+	swaggerFiles "github.com/swaggo/files/v2"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/uc"
+)
+
+const docsPath = "/docs/"
+
+//go:embed openapi.yaml
+var openApiDocument []byte
+
+// swaggerInitializer replaces the one bundled with Swagger UI, which points
+// at the petstore example.
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    persistAuthorization: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+func init() {
+
+	openapi3filter.RegisterBodyDecoder(mergePatchMediaType, openapi3filter.JSONBodyDecoder)
+}
+
+// openApi is the document describing the routes of the app server, and the
+// router used to find the operation a request is validated against.
+type openApi struct {
+	doc    *openapi3.T
+	json   []byte
+	router routers.Router
+}
+
+func loadOpenApi() (*openApi, error) {
+
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(openApiDocument)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse openapi document: %w", err)
+	}
+
+	if err = doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %w", err)
+	}
+
+	data, err := doc.MarshalJSON()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not encode openapi document: %w", err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not route openapi document: %w", err)
+	}
+
+	return &openApi{doc, data, router}, nil
+}
+
+func (api *openApi) hasOperation(path string, method string) bool {
+
+	pathItem := api.doc.Paths.Value(path)
+
+	return pathItem != nil && pathItem.GetOperation(method) != nil
+}
+
+func newOpenApiHandler(api *openApi) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if _, err := w.Write(api.json); err != nil {
+			logger.Instance().Error(r.Context(), err.Error())
+		}
+	}
+}
+
+// newDocsHandler serves Swagger UI, pointed at /openapi.json.
+func newDocsHandler() http.Handler {
+
+	files := http.StripPrefix(docsPath, http.FileServer(http.FS(swaggerFiles.FS)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path == docsPath+"swagger-initializer.js" {
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			fmt.Fprint(w, swaggerInitializer)
+			return
+		}
+
+		files.ServeHTTP(w, r)
+	})
+}
+
+// newValidationMiddleware rejects requests that do not match the parameters
+// or body of their operation in the document. Authentication is left to the
+// auth middleware, and requests the document does not describe are left to
+// the router.
+func newValidationMiddleware(api *openApi) mux.MiddlewareFunc {
+
+	options := &openapi3filter.Options{
+		MultiError:          true,
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults: true,
+	}
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			route, pathParams, err := api.router.FindRoute(r)
+
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+
+			if err = openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				handleClientError(w, r, requestValidationError(err))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestValidationError reports every problem found by the validator as a
+// field error, naming parameters as they are sent and body fields by their
+// path in the body. A body that cannot be decoded is not a validation error.
+func requestValidationError(err error) error {
+
+	switch e := err.(type) {
+	case openapi3.MultiError:
+
+		errs := make([]error, 0, len(e))
+
+		for _, inner := range e {
+			errs = append(errs, requestValidationError(inner))
+		}
+
+		return errors.Join(errs...)
+	case *openapi3filter.RequestError:
+
+		if e.Parameter != nil {
+			return fieldValidationError(e.Parameter.Name, e)
+		}
+
+		if parseErr, ok := e.Err.(*openapi3filter.ParseError); ok {
+			return fmt.Errorf("malformed request body: %s", parseErr.Error())
+		}
+
+		return fieldValidationError("body", e)
+	default:
+		return err
+	}
+}
+
+func fieldValidationError(field string, err *openapi3filter.RequestError) error {
+
+	switch inner := err.Err.(type) {
+	case nil:
+		return &uc.FieldError{Field: field, Reason: err.Reason}
+	case openapi3.MultiError:
+
+		errs := make([]error, 0, len(inner))
+
+		for _, e := range inner {
+			errs = append(errs, schemaFieldError(field, err.Parameter == nil, e))
+		}
+
+		return errors.Join(errs...)
+	default:
+		return schemaFieldError(field, err.Parameter == nil, inner)
+	}
+}
+
+// schemaFieldError names body fields by their path, as the schema errors of
+// a body are reported against the body as a whole.
+func schemaFieldError(field string, inBody bool, err error) *uc.FieldError {
+
+	schemaErr, ok := err.(*openapi3.SchemaError)
+
+	if !ok {
+		return &uc.FieldError{Field: field, Reason: err.Error()}
+	}
+
+	if pointer := schemaErr.JSONPointer(); inBody && len(pointer) > 0 {
+		field = strings.Join(pointer, ".")
+	}
+
+	return &uc.FieldError{Field: field, Reason: schemaErr.Reason}
+}
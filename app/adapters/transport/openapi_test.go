@@ -0,0 +1,155 @@
+package transport
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/utsavgupta/knowledge-hub/app/metrics"
+)
+
+func TestOpenApiDescribesEveryRoute(t *testing.T) {
+
+	api, err := loadOpenApi()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := newRouter(HttpRunnerDependencies{Metrics: metrics.NewMetrics()}, api, &atomic.Bool{})
+	routes := 0
+
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+
+		path, err := route.GetPathTemplate()
+
+		if err != nil {
+			return nil
+		}
+
+		// Subrouters have a path but no methods.
+		methods, err := route.GetMethods()
+
+		if err != nil {
+			return nil
+		}
+
+		for _, method := range methods {
+
+			routes++
+
+			if !api.hasOperation(path, method) {
+				t.Errorf("%s %s is not described in openapi.yaml", method, path)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if routes < 1 {
+		t.Fatal("found no routes to check")
+	}
+}
+
+func TestValidationMiddleware(t *testing.T) {
+
+	api, err := loadOpenApi()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		status      int
+		code        string
+		fields      []string
+		reachesNext bool
+	}{
+		{name: "invalid query", method: http.MethodGet, target: "/domains?limit=500&order=up", status: http.StatusBadRequest, code: "validation_failed", fields: []string{"limit", "order"}},
+		{name: "invalid body", method: http.MethodPost, target: "/domains", body: `{"name": 5, "dailyTokenBudget": -1}`, status: http.StatusBadRequest, code: "validation_failed", fields: []string{"id", "name", "dailyTokenBudget"}},
+		{name: "malformed body", method: http.MethodPost, target: "/domains", body: `{"id": `, status: http.StatusBadRequest, code: "invalid_request"},
+		{name: "valid query", method: http.MethodGet, target: "/domains?limit=10&sort=name", status: http.StatusNoContent, reachesNext: true},
+		{name: "valid body", method: http.MethodPost, target: "/domains", body: `{"id": "docs", "name": "Docs"}`, status: http.StatusNoContent, reachesNext: true},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			var received []byte
+			reached := false
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+				reached = true
+				received, _ = io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			r := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+
+			if len(test.body) > 0 {
+				r.Header.Set("Content-Type", "application/json")
+			}
+
+			w := httptest.NewRecorder()
+			newValidationMiddleware(api)(next).ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, w.Code, w.Body.String())
+			}
+
+			if reached != test.reachesNext {
+				t.Fatalf("expected the handler to be reached: %t, got %t", test.reachesNext, reached)
+			}
+
+			if test.reachesNext {
+
+				if string(received) != test.body {
+					t.Errorf("expected the handler to read %q, got %q", test.body, string(received))
+				}
+
+				return
+			}
+
+			if contentType := w.Header().Get("Content-Type"); contentType != problemMediaType {
+				t.Errorf("expected content type %s, got %s", problemMediaType, contentType)
+			}
+
+			var p problem
+
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+
+			if p.Code != test.code {
+				t.Errorf("expected code %s, got %s", test.code, p.Code)
+			}
+
+			fields := make(map[string]bool, len(p.Errors))
+
+			for _, fieldErr := range p.Errors {
+				fields[fieldErr.Field] = true
+			}
+
+			for _, field := range test.fields {
+				if !fields[field] {
+					t.Errorf("expected an error for field %s, got %v", field, p.Errors)
+				}
+			}
+		})
+	}
+}
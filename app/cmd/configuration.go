@@ -5,133 +5,524 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/utsavgupta/knowledge-hub/app/adapters/datasources"
+	"github.com/utsavgupta/knowledge-hub/app/adapters/outbound"
 	"github.com/utsavgupta/knowledge-hub/app/adapters/transport"
+	"github.com/utsavgupta/knowledge-hub/app/config"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/metrics"
+	"github.com/utsavgupta/knowledge-hub/app/migrations"
 	"github.com/utsavgupta/knowledge-hub/app/repos"
 	"github.com/utsavgupta/knowledge-hub/app/runners"
 	"github.com/utsavgupta/knowledge-hub/app/services"
+	"github.com/utsavgupta/knowledge-hub/app/tracing"
 	"github.com/utsavgupta/knowledge-hub/app/uc"
 )
 
-func configureHttpRunner() (runners.Runner, error) {
+func configureHttpRunner(cfg *config.Config, resources *sharedResources) (runners.Runner, error) {
+
+	var appMetrics *metrics.Metrics
+
+	if cfg.Metrics.Enabled {
+		appMetrics = metrics.NewMetrics()
+	}
+
+	runnerDependencies, err := createHttpRunnerDependencies(cfg, resources, outbound.NewTransport(getOutboundConfig(cfg.Outbound)), appMetrics)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.Auth.Enabled {
+		logger.Instance().Warn(context.Background(), "authentication is disabled, anyone who can reach the app server can manage every domain")
+		runnerDependencies.AuthenticateUc = nil
+	}
+
+	return transport.NewHttpRunner(getHttpServerConfig(cfg.Server), traceHttpRunnerDependencies(*runnerDependencies)), nil
+}
+
+// createHttpRunnerDependencies wires the use cases of the app server. When
+// appMetrics is set, the upstream repos and services and the search use cases
+// are wrapped to record metrics.
+func createHttpRunnerDependencies(cfg *config.Config, resources *sharedResources, outboundTransport *outbound.Transport, appMetrics *metrics.Metrics) (*transport.HttpRunnerDependencies, error) {
 
-	var postgresConnString string
-	var weaviateHost *url.URL
-	var openaiAccessKey string
-	var port int
 	var err error
+	var pgConnPool *pgxpool.Pool
+	var healthProbes []services.HealthProbe
+
+	httpClient := newOutboundClient(outboundTransport)
+
+	// Weaviate is called through the outbound transport as well, and its
+	// breaker is reported by /readyz from the start.
+	if cfg.VectorStore.Store == config.VectorStoreWeaviate {
+		weaviateHost, _ := url.Parse(cfg.VectorStore.WeaviateHost)
+		outboundTransport.Track(weaviateHost.Host)
+	}
+
+	if pgConnPool, err = resources.pgConnPool(cfg.Postgres.ConnString); err != nil {
+		return nil, err
+	}
+
+	if cfg.Server.MigrateOnStart {
+
+		if err = migrateUp(cfg, pgConnPool); err != nil {
+			return nil, err
+		}
+	}
 
-	if postgresConnString, err = getStringFromEnv("kh_pg_conn_str"); err != nil {
+	var domainRepo repos.DomainRepo
+	var resourceRepo repos.ResourceRepo
+	var responseRepo repos.StreamingResponseRepo
+	var chunkRepo repos.ChunkRepo
+	var conversationRepo repos.ConversationRepo
+	var apiKeyRepo repos.ApiKeyRepo
+	var rateLimitRepo repos.RateLimitRepo
+	var budgetRepo repos.BudgetRepo
+	var llm services.LLMClient
+	var conceptService services.ConceptService
+	var condenseService services.CondenseService
+
+	if llm, err = datasources.NewOpenAICompatibleLLM(httpClient, getLLMConfig(cfg.LLM)); err != nil {
+		return nil, err
+	}
+
+	if appMetrics != nil {
+		llm = appMetrics.InstrumentLLMClient(llm)
+	}
+
+	conceptService = tracing.TraceConceptService(datasources.NewConceptLLM(llm))
+	condenseService = tracing.TraceCondenseService(datasources.NewCondenseLLM(llm))
+
+	if domainRepo, err = datasources.NewPGDomainRepo(pgConnPool); err != nil {
+		return nil, err
+	}
+
+	if resourceRepo, err = datasources.NewPGResourceRepo(pgConnPool); err != nil {
+		return nil, err
+	}
+
+	if responseRepo, err = createResponseRepo(cfg, pgConnPool, httpClient, llm, appMetrics); err != nil {
 		return nil, err
 	}
 
-	if weaviateHost, err = getURLFromEnv("kh_weaviate_host"); err != nil {
+	if chunkRepo, err = createChunkRepo(cfg, pgConnPool, httpClient); err != nil {
 		return nil, err
 	}
 
-	if openaiAccessKey, err = getStringFromEnv("kh_openai_api_key"); err != nil {
+	if conversationRepo, err = datasources.NewPGConversationRepo(pgConnPool); err != nil {
 		return nil, err
 	}
 
-	if port, err = getIntFromEnv("kh_app_port"); err != nil {
+	if apiKeyRepo, err = datasources.NewPGApiKeyRepo(pgConnPool); err != nil {
 		return nil, err
 	}
 
-	runnerDependencies, err := createHttpRunnerDependencies(postgresConnString, weaviateHost, openaiAccessKey)
+	if rateLimitRepo, err = datasources.NewPGRateLimitRepo(pgConnPool); err != nil {
+		return nil, err
+	}
+
+	if budgetRepo, err = datasources.NewPGBudgetRepo(pgConnPool); err != nil {
+		return nil, err
+	}
+
+	if healthProbes, err = createHealthProbes(cfg, pgConnPool); err != nil {
+		return nil, err
+	}
+
+	if appMetrics != nil {
+		chunkRepo = appMetrics.InstrumentChunkRepo(cfg.VectorStore.Store, chunkRepo)
+		appMetrics.Register(metrics.NewPoolCollector(pgConnPool), metrics.NewQueueDepthCollector(resourceRepo))
+	}
+
+	clientLimit, domainLimit := getRateLimits(cfg.RateLimit)
+	budgetLimits := uc.BudgetLimits{DailyTokens: cfg.Budget.DailyTokens, MonthlyTokens: cfg.Budget.MonthlyTokens}
+
+	domainStatusValidator := tracing.TraceDomainStatusValidator(uc.NewDomainStatusValidator(domainRepo, resourceRepo))
+	checkBudgetUc := tracing.TraceCheckBudgetUc(uc.NewCheckBudgetUc(budgetRepo, domainRepo, budgetLimits))
+	recordUsageUc := tracing.TraceRecordUsageUc(uc.NewRecordUsageUc(budgetRepo))
+	// Search is traced here rather than with the other use cases, so that its
+	// calls from send message get a span as well.
+	searchUc := tracing.TraceSearchUc(uc.NewSearchUc(domainStatusValidator, domainRepo, checkBudgetUc, recordUsageUc, responseRepo, conceptService, condenseService))
+	streamSearchUc := uc.NewStreamSearchUc(domainStatusValidator, domainRepo, checkBudgetUc, recordUsageUc, responseRepo, conceptService)
+	sendMessageUc := uc.NewSendMessageUc(conversationRepo, searchUc)
+
+	if appMetrics != nil {
+		searchUc = appMetrics.InstrumentSearchUc(searchUc)
+		streamSearchUc = appMetrics.InstrumentStreamSearchUc(streamSearchUc)
+		sendMessageUc = appMetrics.InstrumentSendMessageUc(sendMessageUc)
+	}
+
+	return &transport.HttpRunnerDependencies{
+		SearchUc:            searchUc,
+		StreamSearchUc:      streamSearchUc,
+		ListDomainsUc:       uc.NewListDomainsUc(domainRepo),
+		AddDomainUc:         uc.NewAddDomainUc(domainRepo, chunkRepo),
+		DeleteDomainUc:      uc.NewDeleteDomainUc(domainRepo, resourceRepo, chunkRepo),
+		GetDomainUc:         uc.NewGetDomainUc(domainRepo),
+		UpdateDomainUc:      uc.NewUpdateDomainUc(domainRepo),
+		ListResourcesUc:     uc.NewListResourcesUc(resourceRepo),
+		AddResourceUc:       uc.NewAddResourceUc(resourceRepo, domainRepo),
+		DeleteResourceUc:    uc.NewDeleteResourceUc(resourceRepo, chunkRepo),
+		ReingestResourceUc:  uc.NewReingestResourceUc(resourceRepo),
+		GetResourceUc:       uc.NewGetResourceUc(resourceRepo),
+		UpdateResourceUc:    uc.NewUpdateResourceUc(resourceRepo),
+		StartConversationUc: uc.NewStartConversationUc(conversationRepo, domainRepo),
+		GetConversationUc:   uc.NewGetConversationUc(conversationRepo),
+		SendMessageUc:       sendMessageUc,
+		MintApiKeyUc:        uc.NewMintApiKeyUc(apiKeyRepo, domainRepo),
+		ListApiKeysUc:       uc.NewListApiKeysUc(apiKeyRepo),
+		RevokeApiKeyUc:      uc.NewRevokeApiKeyUc(apiKeyRepo),
+		ThrottleUc:          uc.NewThrottleUc(rateLimitRepo, domainRepo, clientLimit, domainLimit),
+		AuthenticateUc:      uc.NewAuthenticateUc(apiKeyRepo, cfg.Auth.BootstrapAdminKey),
+		CheckReadinessUc:    uc.NewCheckReadinessUc(healthProbes, time.Duration(cfg.Readiness.TimeoutMs)*time.Millisecond, outboundTransport.BreakerStates),
+		Metrics:             appMetrics,
+	}, nil
+}
+
+// traceHttpRunnerDependencies starts a span for every use case called by the
+// app server, except search which is traced where it is built.
+func traceHttpRunnerDependencies(dependencies transport.HttpRunnerDependencies) transport.HttpRunnerDependencies {
+
+	dependencies.StreamSearchUc = tracing.TraceStreamSearchUc(dependencies.StreamSearchUc)
+	dependencies.ListDomainsUc = tracing.TraceListDomainsUc(dependencies.ListDomainsUc)
+	dependencies.AddDomainUc = tracing.TraceAddDomainUc(dependencies.AddDomainUc)
+	dependencies.DeleteDomainUc = tracing.TraceDeleteDomainUc(dependencies.DeleteDomainUc)
+	dependencies.GetDomainUc = tracing.TraceGetDomainUc(dependencies.GetDomainUc)
+	dependencies.UpdateDomainUc = tracing.TraceUpdateDomainUc(dependencies.UpdateDomainUc)
+	dependencies.ListResourcesUc = tracing.TraceListResourcesUc(dependencies.ListResourcesUc)
+	dependencies.AddResourceUc = tracing.TraceAddResourceUc(dependencies.AddResourceUc)
+	dependencies.DeleteResourceUc = tracing.TraceDeleteResourceUc(dependencies.DeleteResourceUc)
+	dependencies.ReingestResourceUc = tracing.TraceReingestResourceUc(dependencies.ReingestResourceUc)
+	dependencies.GetResourceUc = tracing.TraceGetResourceUc(dependencies.GetResourceUc)
+	dependencies.UpdateResourceUc = tracing.TraceUpdateResourceUc(dependencies.UpdateResourceUc)
+	dependencies.StartConversationUc = tracing.TraceStartConversationUc(dependencies.StartConversationUc)
+	dependencies.GetConversationUc = tracing.TraceGetConversationUc(dependencies.GetConversationUc)
+	dependencies.SendMessageUc = tracing.TraceSendMessageUc(dependencies.SendMessageUc)
+	dependencies.MintApiKeyUc = tracing.TraceMintApiKeyUc(dependencies.MintApiKeyUc)
+	dependencies.ListApiKeysUc = tracing.TraceListApiKeysUc(dependencies.ListApiKeysUc)
+	dependencies.RevokeApiKeyUc = tracing.TraceRevokeApiKeyUc(dependencies.RevokeApiKeyUc)
+	dependencies.ThrottleUc = tracing.TraceThrottleUc(dependencies.ThrottleUc)
+
+	if dependencies.AuthenticateUc != nil {
+		dependencies.AuthenticateUc = tracing.TraceAuthenticateUc(dependencies.AuthenticateUc)
+	}
+
+	return dependencies
+}
+
+func configureIngestionWorker(cfg *config.Config, resources *sharedResources) (runners.Runner, error) {
+
+	pgConnPool, err := resources.pgConnPool(cfg.Postgres.ConnString)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resourceRepo, err := datasources.NewPGResourceRepo(pgConnPool)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return transport.NewHttpRunner(port, *runnerDependencies), nil
+	httpClient := newOutboundClient(outbound.NewTransport(getOutboundConfig(cfg.Outbound)))
+	chunkRepo, err := createChunkRepo(cfg, pgConnPool, httpClient)
+
+	if err != nil {
+		return nil, err
+	}
+
+	retryPolicy := uc.RetryPolicy{
+		MaxAttempts: cfg.Worker.MaxAttempts,
+		BaseDelay:   time.Duration(cfg.Worker.RetryBaseDelaySeconds) * time.Second,
+		MaxDelay:    time.Duration(cfg.Worker.RetryMaxDelaySeconds) * time.Second,
+	}
+
+	contentService := datasources.NewContentHTTP(httpClient, int64(cfg.Worker.MaxContentBytes))
+	embeddingService, err := datasources.NewEmbeddingOpenAI(httpClient, getLLMConfig(cfg.LLM))
+
+	if err != nil {
+		return nil, err
+	}
+
+	ingestUc := tracing.TraceIngestNextResourceUc(uc.NewIngestNextResourceUc(resourceRepo, chunkRepo, contentService, embeddingService, retryPolicy))
+	releaseUc := uc.NewReleaseStaleResourcesUc(resourceRepo, time.Duration(cfg.Worker.LeaseTimeoutSeconds)*time.Second, retryPolicy)
+
+	return runners.NewIngestionWorker(cfg.Worker.Concurrency, time.Duration(cfg.Worker.PollIntervalSeconds)*time.Second, time.Duration(cfg.Server.ShutdownTimeoutSeconds)*time.Second, ingestUc, releaseUc), nil
 }
 
-func createHttpRunnerDependencies(postgresConnString string, weaviateHost *url.URL, openaiAccessKey string) (*transport.HttpRunnerDependencies, error) {
+// configureRateLimitPurger runs next to the app server, which is what creates
+// rate limit buckets.
+func configureRateLimitPurger(cfg *config.Config, resources *sharedResources) (runners.Runner, error) {
 
-	var err error
-	var pgConnPool *pgxpool.Pool
+	pgConnPool, err := resources.pgConnPool(cfg.Postgres.ConnString)
 
-	if pgConnPool, err = createPgConnectionPool(postgresConnString); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
-	var domainRepo repos.DomainRepo
-	var resourceRepo repos.ResourceRepo
-	var responseRepo repos.ResponseRepo
-	var conceptService services.ConceptService
+	rateLimitRepo, err := datasources.NewPGRateLimitRepo(pgConnPool)
 
-	conceptService = datasources.NewConceptOpenAI(http.DefaultClient, openaiAccessKey)
+	if err != nil {
+		return nil, err
+	}
 
-	if domainRepo, err = datasources.NewPGDomainRepo(pgConnPool); err != nil {
+	clientLimit, domainLimit := getRateLimits(cfg.RateLimit)
+
+	return runners.NewRateLimitPurger(rateLimitPurgeInterval, uc.NewPurgeRateLimitsUc(rateLimitRepo, clientLimit, domainLimit)), nil
+}
+
+func configureRefreshScheduler(cfg *config.Config, resources *sharedResources) (runners.Runner, error) {
+
+	pgConnPool, err := resources.pgConnPool(cfg.Postgres.ConnString)
+
+	if err != nil {
 		return nil, err
 	}
 
-	if resourceRepo, err = datasources.NewPGResourceRepo(pgConnPool); err != nil {
+	resourceRepo, err := datasources.NewPGResourceRepo(pgConnPool)
+
+	if err != nil {
 		return nil, err
 	}
 
-	if responseRepo, err = datasources.NewWeaviateResponseRepo(weaviateHost.Scheme, weaviateHost.Host, openaiAccessKey); err != nil {
+	return runners.NewRefreshScheduler(time.Duration(cfg.Scheduler.IntervalSeconds)*time.Second, tracing.TraceRequeueDueResourcesUc(uc.NewRequeueDueResourcesUc(resourceRepo))), nil
+}
+
+func configureMigrateRunner(cfg *config.Config, resources *sharedResources, args []string) (runners.Runner, error) {
+
+	var steps int
+	var err error
+
+	if len(args) < 1 {
+		return nil, fmt.Errorf("expected one of migrate up, migrate down N, migrate status")
+	}
+
+	switch args[0] {
+	case runners.MigrateUp, runners.MigrateStatus:
+	case runners.MigrateDown:
+		if len(args) < 2 {
+			return nil, fmt.Errorf("migrate down expects the number of migrations to revert")
+		}
+
+		if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+			return nil, fmt.Errorf("migrate down expects a positive number of migrations to revert")
+		}
+	default:
+		return nil, fmt.Errorf("unknown migrate command %s. expected one of up, down, status", args[0])
+	}
+
+	pgConnPool, err := resources.pgConnPool(cfg.Postgres.ConnString)
+
+	if err != nil {
 		return nil, err
 	}
 
-	domainStatusValidator := uc.NewDomainStatusValidator(resourceRepo)
+	migrator, err := migrations.NewPGMigrator(pgConnPool, migrationFeatures(cfg)...)
 
-	return &transport.HttpRunnerDependencies{
-		SearchUc:         uc.NewSearchUc(domainStatusValidator, responseRepo, conceptService),
-		ListDomainsUc:    uc.NewListDomainsUc(domainRepo),
-		AddDomainUc:      uc.NewAddDomainUc(domainRepo),
-		DeleteDomainUc:   uc.NewDeleteDomainUc(domainRepo),
-		ListResourcesUc:  uc.NewListResourcesUc(resourceRepo),
-		AddResourceUc:    uc.NewAddResourceUc(resourceRepo, domainRepo),
-		DeleteResourceUc: uc.NewDeleteResourceUc(resourceRepo),
-	}, nil
+	if err != nil {
+		return nil, err
+	}
+
+	return runners.NewMigrateRunner(migrator, args[0], steps), nil
 }
 
-func createPgConnectionPool(connStr string) (*pgxpool.Pool, error) {
+// migrationFeatures enables the migrations of the pgvector store when it is
+// the one configured.
+func migrationFeatures(cfg *config.Config) []string {
 
-	return pgxpool.New(context.Background(), connStr)
+	if cfg.VectorStore.Store == config.VectorStorePGVector {
+		return []string{config.VectorStorePGVector}
+	}
+
+	return nil
 }
 
-func getStringFromEnv(name string) (string, error) {
+func migrateUp(cfg *config.Config, pgConnPool *pgxpool.Pool) error {
 
-	if v, ok := os.LookupEnv(name); ok {
+	migrator, err := migrations.NewPGMigrator(pgConnPool, migrationFeatures(cfg)...)
 
-		return v, nil
+	if err != nil {
+		return err
 	}
 
-	return "", fmt.Errorf("environment variable %s not found", name)
+	return runners.NewMigrateRunner(migrator, runners.MigrateUp, 0).Run(context.Background())
 }
 
-func getIntFromEnv(name string) (int, error) {
+func createResponseRepo(cfg *config.Config, pgConnPool *pgxpool.Pool, httpClient *http.Client, llm services.LLMClient, appMetrics *metrics.Metrics) (repos.StreamingResponseRepo, error) {
 
-	if v, ok := os.LookupEnv(name); ok {
+	var citationRepo repos.CitationRepo
+	var err error
+
+	if cfg.VectorStore.Store == config.VectorStorePGVector {
+
+		embeddingService, err := datasources.NewEmbeddingOpenAI(httpClient, getLLMConfig(cfg.LLM))
 
-		if num, err := strconv.Atoi(v); err == nil {
+		if err != nil {
+			return nil, err
+		}
 
-			return num, nil
+		if appMetrics != nil {
+			embeddingService = appMetrics.InstrumentEmbeddingService(embeddingService)
 		}
 
-		return -1, fmt.Errorf("environment variable %s is not an integer", name)
+		citationRepo, err = datasources.NewPGVectorCitationRepo(pgConnPool, embeddingService, cfg.Search.RetrievalLimit, cfg.LLM.EmbeddingDimensions)
+	} else {
+
+		weaviateHost, _ := url.Parse(cfg.VectorStore.WeaviateHost)
+		citationRepo, err = datasources.NewWeaviateCitationRepo(httpClient, cfg.Search.RetrievalLimit, weaviateHost.Scheme, weaviateHost.Host, cfg.VectorStore.WeaviateVectorizerKeyHeader, cfg.OpenAI.APIKey)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if appMetrics != nil {
+		citationRepo = appMetrics.InstrumentCitationRepo(cfg.VectorStore.Store, citationRepo)
+	}
+
+	return tracing.TraceResponseRepo(cfg.VectorStore.Store, datasources.NewLLMResponseRepo(citationRepo, llm)), nil
+}
+
+func createChunkRepo(cfg *config.Config, pgConnPool *pgxpool.Pool, httpClient *http.Client) (repos.ChunkRepo, error) {
+
+	if cfg.VectorStore.Store == config.VectorStorePGVector {
+
+		return datasources.NewPGVectorChunkRepo(pgConnPool, cfg.LLM.EmbeddingDimensions)
 	}
 
-	return -1, fmt.Errorf("environment variable %s not found", name)
+	weaviateHost, _ := url.Parse(cfg.VectorStore.WeaviateHost)
+
+	return datasources.NewWeaviateChunkRepo(httpClient, weaviateHost.Scheme, weaviateHost.Host, cfg.VectorStore.WeaviateVectorizerKeyHeader, cfg.OpenAI.APIKey)
 }
 
-func getURLFromEnv(name string) (*url.URL, error) {
+// createHealthProbes checks Postgres, Weaviate when it is the vector store
+// and, if asked to, the LLM provider. The LLM is probed without the outbound
+// transport so that probes neither retry nor trip its circuit breaker.
+func createHealthProbes(cfg *config.Config, pgConnPool *pgxpool.Pool) ([]services.HealthProbe, error) {
 
-	if v, ok := os.LookupEnv(name); ok {
+	probes := []services.HealthProbe{datasources.NewPGHealthProbe(pgConnPool)}
 
-		if uri, err := url.Parse(v); err == nil {
+	if cfg.VectorStore.Store == config.VectorStoreWeaviate {
 
-			return uri, nil
+		weaviateHost, _ := url.Parse(cfg.VectorStore.WeaviateHost)
+		probe, err := datasources.NewWeaviateHealthProbe(weaviateHost.Scheme, weaviateHost.Host, cfg.VectorStore.WeaviateVectorizerKeyHeader, cfg.OpenAI.APIKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		probes = append(probes, probe)
+	}
+
+	if cfg.Readiness.CheckLLM {
+
+		probe, err := datasources.NewLLMHealthProbe(&http.Client{Transport: tracing.NewTransport(http.DefaultTransport)}, getLLMConfig(cfg.LLM))
+
+		if err != nil {
+			return nil, err
 		}
 
-		return nil, fmt.Errorf("environment variable %s is not a url", name)
+		probes = append(probes, probe)
+	}
+
+	return probes, nil
+}
+
+func createPgConnectionPool(connStr string) (*pgxpool.Pool, error) {
+
+	config, err := pgxpool.ParseConfig(connStr)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse postgres connection string: %w", err)
+	}
+
+	config.ConnConfig.Tracer = tracing.NewPgxTracer()
+
+	return pgxpool.NewWithConfig(context.Background(), config)
+}
+
+// sharedResources creates the resources used by the runners of a process
+// once, and closes them when the lifecycle ends.
+type sharedResources struct {
+	lifecycle  *runners.Lifecycle
+	connString string
+	pool       *pgxpool.Pool
+}
+
+func (resources *sharedResources) pgConnPool(connStr string) (*pgxpool.Pool, error) {
+
+	if resources.pool != nil && resources.connString == connStr {
+		return resources.pool, nil
 	}
 
-	return nil, fmt.Errorf("environment variable %s not found", name)
+	pool, err := createPgConnectionPool(connStr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resources.lifecycle.OnClose("postgres connection pool", func(context.Context) error {
+		pool.Close()
+		return nil
+	})
+
+	resources.connString, resources.pool = connStr, pool
+
+	return pool, nil
+}
+
+// newOutboundClient traces the calls made to OpenAI, Weaviate and the ingested
+// pages.
+// A call gets a single span, which covers its retries.
+func newOutboundClient(outboundTransport *outbound.Transport) *http.Client {
+
+	return &http.Client{Transport: tracing.NewTransport(outboundTransport)}
+}
+
+func getHttpServerConfig(server config.ServerConfig) transport.HttpServerConfig {
+
+	return transport.HttpServerConfig{
+		Port:            server.Port,
+		ReadTimeout:     time.Duration(server.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout:    time.Duration(server.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:     time.Duration(server.IdleTimeoutSeconds) * time.Second,
+		DrainDelay:      time.Duration(server.DrainSeconds) * time.Second,
+		ShutdownTimeout: time.Duration(server.ShutdownTimeoutSeconds) * time.Second,
+	}
+}
+
+func getOutboundConfig(outboundConfig config.OutboundConfig) outbound.Config {
+
+	return outbound.Config{
+		Timeout:          time.Duration(outboundConfig.TimeoutSeconds) * time.Second,
+		MaxAttempts:      outboundConfig.MaxAttempts,
+		BaseDelay:        time.Duration(outboundConfig.RetryBaseDelayMs) * time.Millisecond,
+		MaxDelay:         time.Duration(outboundConfig.RetryMaxDelaySeconds) * time.Second,
+		FailureThreshold: outboundConfig.BreakerFailureThreshold,
+		OpenDuration:     time.Duration(outboundConfig.BreakerOpenSeconds) * time.Second,
+	}
+}
+
+func getRateLimits(rateLimit config.RateLimitConfig) (uc.RateLimit, uc.RateLimit) {
+
+	return uc.RateLimit{PerMinute: rateLimit.ClientPerMinute, Burst: rateLimit.ClientBurst},
+		uc.RateLimit{PerMinute: rateLimit.DomainPerMinute, Burst: rateLimit.DomainBurst}
+}
+
+func getLLMConfig(llm config.LLMConfig) datasources.LLMConfig {
+
+	return datasources.LLMConfig{
+		BaseURL:          llm.BaseURL,
+		Model:            llm.Model,
+		Temperature:      llm.Temperature,
+		AuthHeader:       llm.AuthHeader,
+		APIKey:           llm.APIKey,
+		StreamUsage:      llm.StreamUsage,
+		EmbeddingBaseURL: llm.EmbeddingBaseURL,
+		EmbeddingModel:   llm.EmbeddingModel,
+	}
 }
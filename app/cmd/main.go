@@ -2,21 +2,164 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
+	"github.com/utsavgupta/knowledge-hub/app/config"
 	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/runners"
+	"github.com/utsavgupta/knowledge-hub/app/tracing"
+)
+
+const (
+	rateLimitPurgeInterval = 10 * time.Minute
+
+	commandAll    = "all"
+	commandConfig = "config"
+	commandPrint  = "print"
 )
 
 func main() {
 
 	logger.InitLogger(logger.NewZeroLogger())
 
-	runner, err := configureHttpRunner()
-
-	if err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		logger.Instance().Error(context.Background(), err.Error())
 		os.Exit(1)
 	}
+}
+
+func run(args []string) error {
+
+	cfg, args, err := config.Load(args, os.LookupEnv, os.Stderr)
+
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 && args[0] == commandConfig {
+
+		if len(args) < 2 || args[1] != commandPrint {
+			return fmt.Errorf("expected config print")
+		}
+
+		return config.Print(os.Stdout, *cfg)
+	}
+
+	commands, err := parseCommands(args)
+
+	if err != nil {
+		return err
+	}
+
+	if err = cfg.Validate(commands); err != nil {
+		return err
+	}
+
+	if err = logger.Instance().SetLevel(cfg.Log.Level); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lifecycle := runners.NewLifecycle(time.Duration(cfg.Server.ShutdownTimeoutSeconds) * time.Second)
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{Exporter: cfg.Tracing.Exporter, ServiceName: cfg.Tracing.ServiceName})
+
+	if err != nil {
+		return err
+	}
+
+	// Registered first so that spans are flushed after everything else is closed.
+	lifecycle.OnClose("tracing", shutdownTracing)
+
+	if err = configureRunners(cfg, lifecycle, commands, args); err != nil {
+		return errors.Join(err, lifecycle.Close())
+	}
+
+	return lifecycle.Run(ctx)
+}
+
+// parseCommands lists the runners named in args. Several of http, worker and
+// scheduler may run in one process, and all runs the three of them.
+func parseCommands(args []string) ([]string, error) {
+
+	if len(args) < 1 {
+		return []string{config.CommandHttp}, nil
+	}
+
+	if args[0] == config.CommandMigrate {
+		return []string{config.CommandMigrate}, nil
+	}
+
+	if len(args) == 1 && args[0] == commandAll {
+		return []string{config.CommandHttp, config.CommandWorker, config.CommandScheduler}, nil
+	}
+
+	for i, command := range args {
+
+		if !slices.Contains([]string{config.CommandHttp, config.CommandWorker, config.CommandScheduler}, command) {
+			return nil, fmt.Errorf("unknown command %s. expected one or more of http, worker, scheduler, or one of all, migrate, config print", command)
+		}
+
+		if slices.Contains(args[:i], command) {
+			return nil, fmt.Errorf("%s is given more than once", command)
+		}
+	}
+
+	return args, nil
+}
+
+// configureRunners adds the runners of commands to the lifecycle. They share
+// the Postgres pool.
+func configureRunners(cfg *config.Config, lifecycle *runners.Lifecycle, commands []string, args []string) error {
+
+	resources := &sharedResources{lifecycle: lifecycle}
+
+	for _, command := range commands {
+
+		var runner runners.Runner
+		var err error
+
+		switch command {
+		case config.CommandHttp:
+			runner, err = configureHttpRunner(cfg, resources)
+		case config.CommandWorker:
+			runner, err = configureIngestionWorker(cfg, resources)
+		case config.CommandScheduler:
+			runner, err = configureRefreshScheduler(cfg, resources)
+		case config.CommandMigrate:
+			runner, err = configureMigrateRunner(cfg, resources, args[1:])
+		}
+
+		if err != nil {
+			return err
+		}
+
+		lifecycle.Add(command, runner)
+
+		if command == config.CommandHttp {
+
+			purger, err := configureRateLimitPurger(cfg, resources)
+
+			if err != nil {
+				return err
+			}
+
+			lifecycle.Add("rate-limit-purger", purger)
+		}
+	}
 
-	runner.Run()
+	return nil
 }
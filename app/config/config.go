@@ -0,0 +1,176 @@
+package config
+
+// Config is the configuration of every runner. It is loaded in layers: the
+// defaults below, a YAML file, environment variables and then flags. Every
+// setting names its environment variable in its env tag. Its flag is the same
+// name without the kh_ prefix and with dashes, e.g. --pg-conn-str.
+// Settings tagged as secret are masked when the configuration is printed.
+type Config struct {
+	Log         LogConfig         `yaml:"log"`
+	Server      ServerConfig      `yaml:"server"`
+	Postgres    PostgresConfig    `yaml:"postgres"`
+	VectorStore VectorStoreConfig `yaml:"vector_store"`
+	OpenAI      OpenAIConfig      `yaml:"openai"`
+	LLM         LLMConfig         `yaml:"llm"`
+	Search      SearchConfig      `yaml:"search"`
+	Outbound    OutboundConfig    `yaml:"outbound"`
+	Auth        AuthConfig        `yaml:"auth"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Budget      BudgetConfig      `yaml:"budget"`
+	Readiness   ReadinessConfig   `yaml:"readiness"`
+	Metrics     MetricsConfig     `yaml:"metrics"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Worker      WorkerConfig      `yaml:"worker"`
+	Scheduler   SchedulerConfig   `yaml:"scheduler"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" env:"kh_log_level"`
+}
+
+type ServerConfig struct {
+	Port                   int  `yaml:"port" env:"kh_app_port"`
+	ReadTimeoutSeconds     int  `yaml:"read_timeout_seconds" env:"kh_server_read_timeout_seconds"`
+	WriteTimeoutSeconds    int  `yaml:"write_timeout_seconds" env:"kh_server_write_timeout_seconds"`
+	IdleTimeoutSeconds     int  `yaml:"idle_timeout_seconds" env:"kh_server_idle_timeout_seconds"`
+	DrainSeconds           int  `yaml:"drain_seconds" env:"kh_shutdown_drain_seconds"`
+	ShutdownTimeoutSeconds int  `yaml:"shutdown_timeout_seconds" env:"kh_shutdown_timeout_seconds"`
+	MigrateOnStart         bool `yaml:"migrate_on_start" env:"kh_migrate_on_start"`
+}
+
+type PostgresConfig struct {
+	ConnString string `yaml:"conn_str" env:"kh_pg_conn_str" secret:"true"`
+}
+
+type VectorStoreConfig struct {
+	Store                       string `yaml:"store" env:"kh_vector_store"`
+	WeaviateHost                string `yaml:"weaviate_host" env:"kh_weaviate_host"`
+	WeaviateVectorizerKeyHeader string `yaml:"weaviate_vectorizer_key_header" env:"kh_weaviate_vectorizer_key_header"`
+}
+
+type OpenAIConfig struct {
+	APIKey string `yaml:"api_key" env:"kh_openai_api_key" secret:"true"`
+}
+
+// LLMConfig describes the OpenAI compatible API used for chat completions and
+// embeddings. Its api key defaults to the OpenAI key, and the base url of
+// embeddings to the one of chat completions.
+type LLMConfig struct {
+	BaseURL          string  `yaml:"base_url" env:"kh_llm_base_url"`
+	Model            string  `yaml:"model" env:"kh_llm_model"`
+	Temperature      float64 `yaml:"temperature" env:"kh_llm_temperature"`
+	AuthHeader       string  `yaml:"auth_header" env:"kh_llm_auth_header"`
+	APIKey           string  `yaml:"api_key" env:"kh_llm_api_key" secret:"true"`
+	StreamUsage      bool    `yaml:"stream_usage" env:"kh_llm_stream_usage"`
+	EmbeddingBaseURL string  `yaml:"embedding_base_url" env:"kh_llm_embedding_base_url"`
+	EmbeddingModel   string  `yaml:"embedding_model" env:"kh_llm_embedding_model"`
+	// EmbeddingDimensions is the size of the vectors of EmbeddingModel, which
+	// the pgvector chunks table must have been created with.
+	EmbeddingDimensions int `yaml:"embedding_dimensions" env:"kh_llm_embedding_dimensions"`
+}
+
+type SearchConfig struct {
+	RetrievalLimit int `yaml:"retrieval_limit" env:"kh_search_retrieval_limit"`
+}
+
+type OutboundConfig struct {
+	TimeoutSeconds          int `yaml:"timeout_seconds" env:"kh_http_timeout_seconds"`
+	MaxAttempts             int `yaml:"max_attempts" env:"kh_http_max_attempts"`
+	RetryBaseDelayMs        int `yaml:"retry_base_delay_ms" env:"kh_http_retry_base_delay_ms"`
+	RetryMaxDelaySeconds    int `yaml:"retry_max_delay_seconds" env:"kh_http_retry_max_delay_seconds"`
+	BreakerFailureThreshold int `yaml:"breaker_failure_threshold" env:"kh_http_breaker_failure_threshold"`
+	BreakerOpenSeconds      int `yaml:"breaker_open_seconds" env:"kh_http_breaker_open_seconds"`
+}
+
+type AuthConfig struct {
+	Enabled           bool   `yaml:"enabled" env:"kh_auth_enabled"`
+	BootstrapAdminKey string `yaml:"bootstrap_admin_key" env:"kh_bootstrap_admin_key" secret:"true"`
+}
+
+type RateLimitConfig struct {
+	ClientPerMinute int `yaml:"client_per_minute" env:"kh_rate_limit_client_per_minute"`
+	ClientBurst     int `yaml:"client_burst" env:"kh_rate_limit_client_burst"`
+	DomainPerMinute int `yaml:"domain_per_minute" env:"kh_rate_limit_domain_per_minute"`
+	DomainBurst     int `yaml:"domain_burst" env:"kh_rate_limit_domain_burst"`
+}
+
+type BudgetConfig struct {
+	DailyTokens   int `yaml:"daily_tokens" env:"kh_budget_daily_tokens"`
+	MonthlyTokens int `yaml:"monthly_tokens" env:"kh_budget_monthly_tokens"`
+}
+
+type ReadinessConfig struct {
+	TimeoutMs int  `yaml:"timeout_ms" env:"kh_ready_timeout_ms"`
+	CheckLLM  bool `yaml:"check_llm" env:"kh_ready_check_llm"`
+}
+
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled" env:"kh_metrics_enabled"`
+}
+
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter" env:"kh_trace_exporter"`
+	ServiceName string `yaml:"service_name" env:"kh_trace_service_name"`
+}
+
+type WorkerConfig struct {
+	Concurrency           int `yaml:"concurrency" env:"kh_worker_concurrency"`
+	PollIntervalSeconds   int `yaml:"poll_interval_seconds" env:"kh_worker_poll_interval_seconds"`
+	LeaseTimeoutSeconds   int `yaml:"lease_timeout_seconds" env:"kh_worker_lease_timeout_seconds"`
+	MaxAttempts           int `yaml:"max_attempts" env:"kh_worker_max_attempts"`
+	RetryBaseDelaySeconds int `yaml:"retry_base_delay_seconds" env:"kh_worker_retry_base_delay_seconds"`
+	RetryMaxDelaySeconds  int `yaml:"retry_max_delay_seconds" env:"kh_worker_retry_max_delay_seconds"`
+	// MaxContentBytes bounds the size of the pages fetched for ingestion.
+	MaxContentBytes int `yaml:"max_content_bytes" env:"kh_worker_max_content_bytes"`
+}
+
+type SchedulerConfig struct {
+	IntervalSeconds int `yaml:"interval_seconds" env:"kh_scheduler_interval_seconds"`
+}
+
+func Default() Config {
+
+	return Config{
+		Log: LogConfig{Level: "INFO"},
+		Server: ServerConfig{
+			ReadTimeoutSeconds:     30,
+			WriteTimeoutSeconds:    120,
+			IdleTimeoutSeconds:     120,
+			DrainSeconds:           5,
+			ShutdownTimeoutSeconds: 30,
+		},
+		VectorStore: VectorStoreConfig{Store: VectorStoreWeaviate, WeaviateVectorizerKeyHeader: "X-OpenAI-Api-Key"},
+		LLM: LLMConfig{
+			BaseURL:             "https://api.openai.com/v1",
+			Model:               "gpt-3.5-turbo",
+			AuthHeader:          "Authorization",
+			StreamUsage:         true,
+			EmbeddingModel:      "text-embedding-ada-002",
+			EmbeddingDimensions: 1536,
+		},
+		Search: SearchConfig{RetrievalLimit: 5},
+		Outbound: OutboundConfig{
+			TimeoutSeconds:          30,
+			MaxAttempts:             3,
+			RetryBaseDelayMs:        500,
+			RetryMaxDelaySeconds:    30,
+			BreakerFailureThreshold: 5,
+			BreakerOpenSeconds:      30,
+		},
+		Auth:      AuthConfig{Enabled: true},
+		RateLimit: RateLimitConfig{ClientPerMinute: 60, ClientBurst: 20, DomainPerMinute: 300, DomainBurst: 50},
+		Readiness: ReadinessConfig{TimeoutMs: 2000},
+		Metrics:   MetricsConfig{Enabled: true},
+		Tracing:   TracingConfig{Exporter: "none", ServiceName: "knowledge-hub"},
+		Worker: WorkerConfig{
+			Concurrency:           1,
+			PollIntervalSeconds:   10,
+			LeaseTimeoutSeconds:   900,
+			MaxAttempts:           5,
+			RetryBaseDelaySeconds: 30,
+			RetryMaxDelaySeconds:  3600,
+			MaxContentBytes:       10 << 20,
+		},
+		Scheduler: SchedulerConfig{IntervalSeconds: 60},
+	}
+}
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	usage          = "Usage: app [flags] [http] [worker] [scheduler] | all | migrate up|down N|status | config print\n\nFlags:"
+	configFileEnv  = "kh_config_file"
+	configFileFlag = "config"
+	envPrefix      = "kh_"
+	secretMask     = "******"
+)
+
+// setting is a leaf of Config along with where it can be set from.
+type setting struct {
+	path   string
+	env    string
+	flag   string
+	secret bool
+	value  reflect.Value
+}
+
+type flagValue struct {
+	setting setting
+	raw     string
+}
+
+// Load builds the configuration from its defaults, the YAML file named by
+// --config or kh_config_file, the environment and the flags in args, in
+// that order. A secret may also be read from the file named by its
+// environment variable with a _FILE suffix, e.g. kh_openai_api_key_FILE.
+// Every problem found is reported at once. Flags may come before, between or
+// after the commands, and the arguments that are not flags are returned.
+// -h writes the usage to output and returns flag.ErrHelp.
+func Load(args []string, lookupEnv func(string) (string, bool), output io.Writer) (*Config, []string, error) {
+
+	config := Default()
+	settings := settingsOf(&config)
+
+	var configFile string
+	var flagValues []flagValue
+
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprintln(output, usage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&configFile, configFileFlag, "", "YAML file to read the configuration from")
+
+	for _, s := range settings {
+
+		s := s
+
+		flags.Func(s.flag, fmt.Sprintf("%s, or %s", s.path, s.env), func(raw string) error {
+			flagValues = append(flagValues, flagValue{s, raw})
+			return nil
+		})
+	}
+
+	rest, err := parseInterleaved(flags, args)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse flags: %w", err)
+	}
+
+	var problems []error
+
+	if len(configFile) < 1 {
+		configFile, _ = lookupEnv(configFileEnv)
+	}
+
+	if len(configFile) > 0 {
+
+		if err := loadFile(&config, configFile); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	for _, s := range settings {
+
+		raw, ok, err := lookupSetting(s, lookupEnv)
+
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if err := setValue(s.value, raw); err != nil {
+			problems = append(problems, fmt.Errorf("environment variable %s: %w", s.env, err))
+		}
+	}
+
+	for _, v := range flagValues {
+
+		if err := setValue(v.setting.value, v.raw); err != nil {
+			problems = append(problems, fmt.Errorf("flag --%s: %w", v.setting.flag, err))
+		}
+	}
+
+	if len(config.LLM.APIKey) < 1 {
+		config.LLM.APIKey = config.OpenAI.APIKey
+	}
+
+	if len(config.LLM.EmbeddingBaseURL) < 1 {
+		config.LLM.EmbeddingBaseURL = config.LLM.BaseURL
+	}
+
+	if len(problems) > 0 {
+		return nil, nil, errors.Join(problems...)
+	}
+
+	return &config, rest, nil
+}
+
+// parseInterleaved parses the flags found anywhere in args, since the flag
+// package stops at the first argument that is not a flag. Everything after
+// -- is left as it is.
+func parseInterleaved(flags *flag.FlagSet, args []string) ([]string, error) {
+
+	var rest []string
+
+	for {
+
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		remaining := flags.Args()
+
+		if parsed := len(args) - len(remaining); parsed > 0 && args[parsed-1] == "--" {
+			return append(rest, remaining...), nil
+		}
+
+		if len(remaining) < 1 {
+			return rest, nil
+		}
+
+		rest = append(rest, remaining[0])
+		args = remaining[1:]
+	}
+}
+
+// Print writes the configuration as YAML with its secrets masked.
+func Print(w io.Writer, config Config) error {
+
+	for _, s := range settingsOf(&config) {
+
+		if s.secret && s.value.Len() > 0 {
+			s.value.SetString(secretMask)
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(config); err != nil {
+		return fmt.Errorf("could not print configuration: %w", err)
+	}
+
+	return encoder.Close()
+}
+
+func loadFile(config *Config, path string) error {
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return fmt.Errorf("could not open configuration file: %w", err)
+	}
+
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(config); err != nil && err != io.EOF {
+		return fmt.Errorf("could not read configuration file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// lookupSetting reads a setting from its environment variable, or from the
+// file named by the variable with a _FILE suffix.
+func lookupSetting(s setting, lookupEnv func(string) (string, bool)) (string, bool, error) {
+
+	raw, ok := lookupEnv(s.env)
+	file, fileOk := lookupEnv(s.env + "_FILE")
+
+	if !fileOk {
+		return raw, ok, nil
+	}
+
+	if ok {
+		return "", false, fmt.Errorf("only one of the environment variables %s and %s_FILE may be set", s.env, s.env)
+	}
+
+	content, err := os.ReadFile(file)
+
+	if err != nil {
+		return "", false, fmt.Errorf("could not read %s from file: %w", s.env, err)
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+func settingsOf(config *Config) []setting {
+
+	var settings []setting
+	collectSettings(reflect.ValueOf(config).Elem(), "", &settings)
+
+	return settings
+}
+
+func collectSettings(v reflect.Value, prefix string, settings *[]setting) {
+
+	for i := 0; i < v.NumField(); i++ {
+
+		field := v.Type().Field(i)
+		path := prefix + field.Tag.Get("yaml")
+
+		if field.Type.Kind() == reflect.Struct {
+			collectSettings(v.Field(i), path+".", settings)
+			continue
+		}
+
+		env := field.Tag.Get("env")
+
+		*settings = append(*settings, setting{
+			path:   path,
+			env:    env,
+			flag:   strings.ReplaceAll(strings.TrimPrefix(env, envPrefix), "_", "-"),
+			secret: field.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+}
+
+func setValue(v reflect.Value, raw string) error {
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		i, err := strconv.Atoi(raw)
+
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+
+		v.SetInt(int64(i))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+
+		v.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("settings of kind %s are not supported", v.Kind())
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name string, content string) string {
+
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func lookupIn(env map[string]string) func(string) (string, bool) {
+
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+
+	configFile := writeFile(t, "config.yaml", "log:\n  level: DEBUG\n")
+
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		level    string
+		commands []string
+	}{
+		{name: "defaults", level: "INFO"},
+		{name: "file over defaults", env: map[string]string{configFileEnv: configFile}, level: "DEBUG"},
+		{name: "file named by flag", args: []string{"--config", configFile}, level: "DEBUG"},
+		{name: "environment over file", env: map[string]string{configFileEnv: configFile, "kh_log_level": "WARN"}, level: "WARN"},
+		{
+			name:  "flags over environment",
+			args:  []string{"--log-level", "ERROR"},
+			env:   map[string]string{configFileEnv: configFile, "kh_log_level": "WARN"},
+			level: "ERROR",
+		},
+		{
+			name:     "flags after the commands",
+			args:     []string{"http", "--log-level", "ERROR", "worker"},
+			env:      map[string]string{"kh_log_level": "WARN"},
+			level:    "ERROR",
+			commands: []string{"http", "worker"},
+		},
+		{
+			name:     "arguments after -- are not flags",
+			args:     []string{"--log-level=ERROR", "--", "config", "--log-level=DEBUG"},
+			level:    "ERROR",
+			commands: []string{"config", "--log-level=DEBUG"},
+		},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			config, commands, err := Load(test.args, lookupIn(test.env), io.Discard)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if config.Log.Level != test.level {
+				t.Fatalf("log level is %s, want %s", config.Log.Level, test.level)
+			}
+
+			if !slices.Equal(commands, test.commands) {
+				t.Fatalf("commands are %v, want %v", commands, test.commands)
+			}
+		})
+	}
+}
+
+func TestLoadDefaultsDerivedSettings(t *testing.T) {
+
+	config, _, err := Load([]string{"--openai-api-key", "sk-openai", "--llm-base-url", "http://llm:8000/v1"}, lookupIn(nil), io.Discard)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.LLM.APIKey != "sk-openai" {
+		t.Fatalf("llm api key is %q, want the openai api key", config.LLM.APIKey)
+	}
+
+	if config.LLM.EmbeddingBaseURL != "http://llm:8000/v1" {
+		t.Fatalf("embedding base url is %q, want the llm base url", config.LLM.EmbeddingBaseURL)
+	}
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+
+	secretFile := writeFile(t, "api_key", "sk-from-file\n")
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		apiKey string
+		err    string
+	}{
+		{name: "read and trimmed", env: map[string]string{"kh_openai_api_key_FILE": secretFile}, apiKey: "sk-from-file"},
+		{
+			name: "both the variable and its file",
+			env:  map[string]string{"kh_openai_api_key": "sk", "kh_openai_api_key_FILE": secretFile},
+			err:  "only one of the environment variables kh_openai_api_key and kh_openai_api_key_FILE may be set",
+		},
+		{
+			name: "missing file",
+			env:  map[string]string{"kh_openai_api_key_FILE": filepath.Join(t.TempDir(), "missing")},
+			err:  "could not read kh_openai_api_key from file",
+		},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			config, _, err := Load(nil, lookupIn(test.env), io.Discard)
+
+			if len(test.err) > 0 {
+
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want one containing %q", err, test.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if config.OpenAI.APIKey != test.apiKey {
+				t.Fatalf("api key is %q, want %q", config.OpenAI.APIKey, test.apiKey)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+
+	env := map[string]string{"kh_app_port": "eighty", "kh_metrics_enabled": "sometimes"}
+
+	_, _, err := Load([]string{"--llm-temperature", "warm"}, lookupIn(env), io.Discard)
+
+	if err == nil {
+		t.Fatal("got no error")
+	}
+
+	for _, want := range []string{
+		`environment variable kh_app_port: "eighty" is not an integer`,
+		`environment variable kh_metrics_enabled: "sometimes" is not a boolean`,
+		`flag --llm-temperature: "warm" is not a number`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownFileFields(t *testing.T) {
+
+	configFile := writeFile(t, "config.yaml", "log:\n  lvl: DEBUG\n")
+
+	if _, _, err := Load([]string{"--config", configFile}, lookupIn(nil), io.Discard); err == nil {
+		t.Fatal("got no error for an unknown field")
+	}
+}
+
+func TestLoadHelp(t *testing.T) {
+
+	var output bytes.Buffer
+
+	if _, _, err := Load([]string{"http", "-h"}, lookupIn(nil), &output); !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("got %v, want %v", err, flag.ErrHelp)
+	}
+
+	if !strings.HasPrefix(output.String(), "Usage: app") || !strings.Contains(output.String(), "-pg-conn-str") {
+		t.Fatalf("usage is missing from %q", output.String())
+	}
+}
+
+func TestPrintMasksSecrets(t *testing.T) {
+
+	config := Default()
+	config.Postgres.ConnString = "postgres://user:password@db/kh"
+	config.OpenAI.APIKey = "sk-secret"
+
+	var output bytes.Buffer
+
+	if err := Print(&output, config); err != nil {
+		t.Fatal(err)
+	}
+
+	printed := output.String()
+
+	for _, secret := range []string{"password", "sk-secret"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("printed configuration reveals %q", secret)
+		}
+	}
+
+	for _, want := range []string{"conn_str: '" + secretMask + "'", "api_key: '" + secretMask + "'", `api_key: ""`, `bootstrap_admin_key: ""`} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("printed configuration does not contain %s", want)
+		}
+	}
+
+	if config.OpenAI.APIKey != "sk-secret" {
+		t.Fatal("printing changed the configuration")
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/utsavgupta/knowledge-hub/app/tracing"
+)
+
+const (
+	VectorStoreWeaviate = "weaviate"
+	VectorStorePGVector = "pgvector"
+
+	CommandHttp      = "http"
+	CommandWorker    = "worker"
+	CommandScheduler = "scheduler"
+	CommandMigrate   = "migrate"
+)
+
+var logLevels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}
+
+// Validate checks the settings needed by the given commands and reports every
+// problem at once.
+func (config Config) Validate(commands []string) error {
+
+	var problems []string
+
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	http := slices.Contains(commands, CommandHttp)
+	worker := slices.Contains(commands, CommandWorker)
+
+	check(slices.Contains(logLevels, strings.ToUpper(config.Log.Level)), "log.level must be one of %s", strings.Join(logLevels, ", "))
+	check(len(config.Postgres.ConnString) > 0, "postgres.conn_str (kh_pg_conn_str) is required")
+	check(slices.Contains([]string{tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout}, config.Tracing.Exporter),
+		"tracing.exporter must be one of %s, %s, %s", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout)
+	check(config.Server.ShutdownTimeoutSeconds > 0, "server.shutdown_timeout_seconds must be positive")
+
+	if http || worker {
+
+		check(len(config.OpenAI.APIKey) > 0, "openai.api_key (kh_openai_api_key) is required")
+		check(config.VectorStore.Store == VectorStoreWeaviate || config.VectorStore.Store == VectorStorePGVector,
+			"vector_store.store must be one of %s, %s", VectorStoreWeaviate, VectorStorePGVector)
+
+		if config.VectorStore.Store == VectorStoreWeaviate {
+			check(isAbsoluteURL(config.VectorStore.WeaviateHost), "vector_store.weaviate_host (kh_weaviate_host) must be a url with a scheme and a host")
+			check(len(config.VectorStore.WeaviateVectorizerKeyHeader) > 0, "vector_store.weaviate_vectorizer_key_header must not be empty")
+		}
+
+		if config.VectorStore.Store == VectorStorePGVector {
+			check(config.LLM.EmbeddingDimensions > 0, "llm.embedding_dimensions (kh_llm_embedding_dimensions) must be positive")
+		}
+
+		check(isAbsoluteURL(config.LLM.BaseURL), "llm.base_url must be a url with a scheme and a host")
+		check(isAbsoluteURL(config.LLM.EmbeddingBaseURL), "llm.embedding_base_url must be a url with a scheme and a host")
+		check(len(config.LLM.EmbeddingModel) > 0, "llm.embedding_model must not be empty")
+		check(len(config.LLM.AuthHeader) > 0, "llm.auth_header must not be empty")
+		checkOutbound(config.Outbound, check)
+	}
+
+	if http {
+
+		check(config.Server.Port > 0 && config.Server.Port < 65536, "server.port (kh_app_port) must be between 1 and 65535")
+		check(config.Server.ReadTimeoutSeconds >= 0, "server.read_timeout_seconds must not be negative")
+		check(config.Server.WriteTimeoutSeconds >= 0, "server.write_timeout_seconds must not be negative")
+		check(config.Server.IdleTimeoutSeconds >= 0, "server.idle_timeout_seconds must not be negative")
+		check(config.Server.DrainSeconds >= 0, "server.drain_seconds must not be negative")
+		check(len(config.LLM.Model) > 0, "llm.model must not be empty")
+		check(config.LLM.Temperature >= 0 && config.LLM.Temperature <= 2, "llm.temperature must be between 0 and 2")
+		check(config.Search.RetrievalLimit > 0, "search.retrieval_limit must be positive")
+		check(config.RateLimit.ClientPerMinute >= 0 && config.RateLimit.ClientBurst >= 0, "rate_limit.client_per_minute and rate_limit.client_burst must not be negative")
+		check(config.RateLimit.DomainPerMinute >= 0 && config.RateLimit.DomainBurst >= 0, "rate_limit.domain_per_minute and rate_limit.domain_burst must not be negative")
+		check(config.Budget.DailyTokens >= 0 && config.Budget.MonthlyTokens >= 0, "budget.daily_tokens and budget.monthly_tokens must not be negative")
+		check(config.Readiness.TimeoutMs > 0, "readiness.timeout_ms must be positive")
+	}
+
+	if worker {
+
+		check(config.Worker.Concurrency > 0, "worker.concurrency must be positive")
+		check(config.Worker.PollIntervalSeconds > 0, "worker.poll_interval_seconds must be positive")
+		check(config.Worker.LeaseTimeoutSeconds > 0, "worker.lease_timeout_seconds must be positive")
+		check(config.Worker.MaxAttempts > 0, "worker.max_attempts must be positive")
+		check(config.Worker.RetryBaseDelaySeconds >= 0 && config.Worker.RetryMaxDelaySeconds >= config.Worker.RetryBaseDelaySeconds,
+			"worker.retry_max_delay_seconds must not be less than worker.retry_base_delay_seconds")
+		check(config.Worker.MaxContentBytes > 0, "worker.max_content_bytes must be positive")
+	}
+
+	if slices.Contains(commands, CommandScheduler) {
+		check(config.Scheduler.IntervalSeconds > 0, "scheduler.interval_seconds must be positive")
+	}
+
+	if len(problems) < 1 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+}
+
+func checkOutbound(outbound OutboundConfig, check func(bool, string, ...any)) {
+
+	check(outbound.TimeoutSeconds > 0, "outbound.timeout_seconds must be positive")
+	check(outbound.MaxAttempts > 0, "outbound.max_attempts must be positive")
+	check(outbound.RetryBaseDelayMs >= 0, "outbound.retry_base_delay_ms must not be negative")
+	check(outbound.RetryMaxDelaySeconds >= 0, "outbound.retry_max_delay_seconds must not be negative")
+	check(outbound.BreakerFailureThreshold > 0, "outbound.breaker_failure_threshold must be positive")
+	check(outbound.BreakerOpenSeconds >= 0, "outbound.breaker_open_seconds must not be negative")
+}
+
+func isAbsoluteURL(raw string) bool {
+
+	u, err := url.Parse(raw)
+
+	return err == nil && len(u.Scheme) > 0 && len(u.Host) > 0
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+var (
+	allCommands = []string{CommandHttp, CommandWorker, CommandScheduler}
+	// clients are the commands that call the LLM and the vector store.
+	clients = []string{CommandHttp, CommandWorker}
+)
+
+func validConfig() Config {
+
+	config := Default()
+	config.Server.Port = 8080
+	config.Postgres.ConnString = "postgres://db/kh"
+	config.OpenAI.APIKey = "sk"
+	config.VectorStore.WeaviateHost = "http://weaviate:8080"
+	config.LLM.EmbeddingBaseURL = config.LLM.BaseURL
+
+	return config
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+
+	if err := validConfig().Validate(allCommands); err != nil {
+		t.Fatal(err)
+	}
+
+	pgvector := validConfig()
+	pgvector.VectorStore.Store = VectorStorePGVector
+	pgvector.VectorStore.WeaviateHost = ""
+
+	if err := pgvector.Validate(allCommands); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		commands []string
+		change   func(*Config)
+		err      string
+	}{
+		{"log level", nil, func(c *Config) { c.Log.Level = "LOUD" }, "log.level must be one of TRACE, DEBUG, INFO, WARN, ERROR"},
+		{"postgres", nil, func(c *Config) { c.Postgres.ConnString = "" }, "postgres.conn_str (kh_pg_conn_str) is required"},
+		{"tracing exporter", nil, func(c *Config) { c.Tracing.Exporter = "jaeger" }, "tracing.exporter must be one of"},
+		{"shutdown timeout", nil, func(c *Config) { c.Server.ShutdownTimeoutSeconds = 0 }, "server.shutdown_timeout_seconds must be positive"},
+		{"openai key", clients, func(c *Config) { c.OpenAI.APIKey = "" }, "openai.api_key (kh_openai_api_key) is required"},
+		{"vector store", clients, func(c *Config) { c.VectorStore.Store = "faiss" }, "vector_store.store must be one of weaviate, pgvector"},
+		{"weaviate host", clients, func(c *Config) { c.VectorStore.WeaviateHost = "weaviate" }, "vector_store.weaviate_host (kh_weaviate_host) must be a url"},
+		{"weaviate key header", clients, func(c *Config) { c.VectorStore.WeaviateVectorizerKeyHeader = "" }, "vector_store.weaviate_vectorizer_key_header must not be empty"},
+		{"embedding dimensions", clients, func(c *Config) {
+			c.VectorStore.Store = VectorStorePGVector
+			c.LLM.EmbeddingDimensions = 0
+		}, "llm.embedding_dimensions (kh_llm_embedding_dimensions) must be positive"},
+		{"llm base url", clients, func(c *Config) { c.LLM.BaseURL = "/v1" }, "llm.base_url must be a url"},
+		{"embedding base url", clients, func(c *Config) { c.LLM.EmbeddingBaseURL = "" }, "llm.embedding_base_url must be a url"},
+		{"embedding model", clients, func(c *Config) { c.LLM.EmbeddingModel = "" }, "llm.embedding_model must not be empty"},
+		{"llm auth header", clients, func(c *Config) { c.LLM.AuthHeader = "" }, "llm.auth_header must not be empty"},
+		{"outbound timeout", clients, func(c *Config) { c.Outbound.TimeoutSeconds = 0 }, "outbound.timeout_seconds must be positive"},
+		{"outbound attempts", clients, func(c *Config) { c.Outbound.MaxAttempts = 0 }, "outbound.max_attempts must be positive"},
+		{"outbound base delay", clients, func(c *Config) { c.Outbound.RetryBaseDelayMs = -1 }, "outbound.retry_base_delay_ms must not be negative"},
+		{"outbound max delay", clients, func(c *Config) { c.Outbound.RetryMaxDelaySeconds = -1 }, "outbound.retry_max_delay_seconds must not be negative"},
+		{"breaker threshold", clients, func(c *Config) { c.Outbound.BreakerFailureThreshold = 0 }, "outbound.breaker_failure_threshold must be positive"},
+		{"breaker open", clients, func(c *Config) { c.Outbound.BreakerOpenSeconds = -1 }, "outbound.breaker_open_seconds must not be negative"},
+		{"port", []string{CommandHttp}, func(c *Config) { c.Server.Port = 70000 }, "server.port (kh_app_port) must be between 1 and 65535"},
+		{"read timeout", []string{CommandHttp}, func(c *Config) { c.Server.ReadTimeoutSeconds = -1 }, "server.read_timeout_seconds must not be negative"},
+		{"write timeout", []string{CommandHttp}, func(c *Config) { c.Server.WriteTimeoutSeconds = -1 }, "server.write_timeout_seconds must not be negative"},
+		{"idle timeout", []string{CommandHttp}, func(c *Config) { c.Server.IdleTimeoutSeconds = -1 }, "server.idle_timeout_seconds must not be negative"},
+		{"drain", []string{CommandHttp}, func(c *Config) { c.Server.DrainSeconds = -1 }, "server.drain_seconds must not be negative"},
+		{"llm model", []string{CommandHttp}, func(c *Config) { c.LLM.Model = "" }, "llm.model must not be empty"},
+		{"temperature", []string{CommandHttp}, func(c *Config) { c.LLM.Temperature = 3 }, "llm.temperature must be between 0 and 2"},
+		{"retrieval limit", []string{CommandHttp}, func(c *Config) { c.Search.RetrievalLimit = 0 }, "search.retrieval_limit must be positive"},
+		{"client rate limit", []string{CommandHttp}, func(c *Config) { c.RateLimit.ClientBurst = -1 }, "rate_limit.client_per_minute and rate_limit.client_burst must not be negative"},
+		{"domain rate limit", []string{CommandHttp}, func(c *Config) { c.RateLimit.DomainPerMinute = -1 }, "rate_limit.domain_per_minute and rate_limit.domain_burst must not be negative"},
+		{"budget", []string{CommandHttp}, func(c *Config) { c.Budget.MonthlyTokens = -1 }, "budget.daily_tokens and budget.monthly_tokens must not be negative"},
+		{"readiness timeout", []string{CommandHttp}, func(c *Config) { c.Readiness.TimeoutMs = 0 }, "readiness.timeout_ms must be positive"},
+		{"worker concurrency", []string{CommandWorker}, func(c *Config) { c.Worker.Concurrency = 0 }, "worker.concurrency must be positive"},
+		{"worker poll interval", []string{CommandWorker}, func(c *Config) { c.Worker.PollIntervalSeconds = 0 }, "worker.poll_interval_seconds must be positive"},
+		{"worker lease timeout", []string{CommandWorker}, func(c *Config) { c.Worker.LeaseTimeoutSeconds = 0 }, "worker.lease_timeout_seconds must be positive"},
+		{"worker attempts", []string{CommandWorker}, func(c *Config) { c.Worker.MaxAttempts = 0 }, "worker.max_attempts must be positive"},
+		{"worker retry delays", []string{CommandWorker}, func(c *Config) { c.Worker.RetryMaxDelaySeconds = 1 }, "worker.retry_max_delay_seconds must not be less than worker.retry_base_delay_seconds"},
+		{"worker content size", []string{CommandWorker}, func(c *Config) { c.Worker.MaxContentBytes = 0 }, "worker.max_content_bytes must be positive"},
+		{"scheduler interval", []string{CommandScheduler}, func(c *Config) { c.Scheduler.IntervalSeconds = 0 }, "scheduler.interval_seconds must be positive"},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			config := validConfig()
+			test.change(&config)
+
+			err := config.Validate(allCommands)
+
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got %v, want an error containing %q", err, test.err)
+			}
+
+			// Settings are only required by the commands that use them.
+			if len(test.commands) > 0 {
+
+				others := make([]string, 0, len(allCommands))
+
+				for _, command := range allCommands {
+					if !slices.Contains(test.commands, command) {
+						others = append(others, command)
+					}
+				}
+
+				if err := config.Validate(others); err != nil && strings.Contains(err.Error(), test.err) {
+					t.Fatalf("%v reported %q, which only %v need", others, test.err, test.commands)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+
+	config := validConfig()
+	config.Postgres.ConnString = ""
+	config.Server.Port = 0
+
+	err := config.Validate([]string{CommandHttp})
+
+	if err == nil {
+		t.Fatal("got no error")
+	}
+
+	want := "invalid configuration:\n  postgres.conn_str (kh_pg_conn_str) is required\n  server.port (kh_app_port) must be between 1 and 65535"
+
+	if err.Error() != want {
+		t.Fatalf("got %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,70 @@
+package entities
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleReader = "reader"
+)
+
+type ApiKey struct {
+	Id        int        `json:"id"`
+	Name      string     `json:"name"`
+	Role      string     `json:"role"`
+	DomainIds []string   `json:"domainIds"`
+	Prefix    string     `json:"prefix"`
+	KeyHash   string     `json:"-"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	// Key is only set when the key is minted, it cannot be retrieved afterwards.
+	Key string `json:"key,omitempty"`
+}
+
+// Identity is the authenticated caller of a request. Admins can access every
+// domain, editors can manage the resources of and search their domains, and
+// readers can only search their domains.
+type Identity struct {
+	KeyId     int      `json:"keyId"`
+	Name      string   `json:"name"`
+	Role      string   `json:"role"`
+	DomainIds []string `json:"domainIds"`
+}
+
+type identityKey struct{}
+
+func (identity Identity) IsAdmin() bool {
+
+	return identity.Role == RoleAdmin
+}
+
+func (identity Identity) CanRead(domainId string) bool {
+
+	return identity.IsAdmin() || identity.hasDomain(domainId)
+}
+
+func (identity Identity) CanEdit(domainId string) bool {
+
+	return identity.IsAdmin() || (identity.Role == RoleEditor && identity.hasDomain(domainId))
+}
+
+func (identity Identity) hasDomain(domainId string) bool {
+
+	return slices.Contains(identity.DomainIds, AllDomains) || slices.Contains(identity.DomainIds, domainId)
+}
+
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller of the request, if it was authenticated.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
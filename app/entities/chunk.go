@@ -0,0 +1,11 @@
+package entities
+
+type Chunk struct {
+	DomainId    string
+	ResourceId  int
+	ContentHash string
+	Index       int
+	Text        string
+	Source      string
+	Vector      []float32
+}
@@ -0,0 +1,11 @@
+package entities
+
+type Citation struct {
+	Rank       int     `json:"rank"`
+	DomainId   string  `json:"domainId"`
+	Text       string  `json:"text"`
+	Source     string  `json:"source"`
+	ResourceId int     `json:"resourceId,omitempty"`
+	Distance   float64 `json:"distance"`
+	Certainty  float64 `json:"certainty"`
+}
@@ -0,0 +1,25 @@
+package entities
+
+import "time"
+
+const (
+	MessageRoleUser      = "user"
+	MessageRoleAssistant = "assistant"
+)
+
+type Conversation struct {
+	Id        int        `json:"id"`
+	DomainId  string     `json:"domainId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	Messages  []Message  `json:"messages"`
+}
+
+type Message struct {
+	Id             int        `json:"id"`
+	ConversationId int        `json:"conversationId"`
+	Role           string     `json:"role"`
+	Content        string     `json:"content"`
+	Sources        []Citation `json:"sources,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
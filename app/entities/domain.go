@@ -3,9 +3,35 @@ package entities
 import "time"
 
 type Domain struct {
-	Id          string     `json:"id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+	Id                 string     `json:"id"`
+	Name               string     `json:"name"`
+	Description        string     `json:"description"`
+	Model              *string    `json:"model,omitempty"`
+	DailyTokenBudget   *int       `json:"dailyTokenBudget,omitempty"`
+	MonthlyTokenBudget *int       `json:"monthlyTokenBudget,omitempty"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          *time.Time `json:"updatedAt,omitempty"`
+}
+
+// DomainPatch holds the fields of a domain that can be changed.
+type DomainPatch struct {
+	Name               Patch[string] `json:"name"`
+	Description        Patch[string] `json:"description"`
+	Model              Patch[string] `json:"model"`
+	DailyTokenBudget   Patch[int]    `json:"dailyTokenBudget"`
+	MonthlyTokenBudget Patch[int]    `json:"monthlyTokenBudget"`
+}
+
+func (domain Domain) Version() time.Time {
+
+	return Version(domain.CreatedAt, domain.UpdatedAt)
+}
+
+func (patch DomainPatch) Apply(domain *Domain) {
+
+	patch.Name.Apply(&domain.Name)
+	patch.Description.Apply(&domain.Description)
+	patch.Model.ApplyOptional(&domain.Model)
+	patch.DailyTokenBudget.ApplyOptional(&domain.DailyTokenBudget)
+	patch.MonthlyTokenBudget.ApplyOptional(&domain.MonthlyTokenBudget)
 }
@@ -0,0 +1,22 @@
+package entities
+
+import "time"
+
+// DependencyHealth is the outcome of the latest probe of a dependency. The
+// last error is kept after the dependency recovers.
+type DependencyHealth struct {
+	Name        string     `json:"name"`
+	Healthy     bool       `json:"healthy"`
+	LatencyMs   int64      `json:"latencyMs"`
+	CheckedAt   time.Time  `json:"checkedAt"`
+	LastError   *string    `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+type Readiness struct {
+	Ready        bool               `json:"ready"`
+	Draining     bool               `json:"draining,omitempty"`
+	Dependencies []DependencyHealth `json:"dependencies"`
+	// Breakers are the states of the circuit breakers of upstream hosts.
+	Breakers map[string]string `json:"breakers,omitempty"`
+}
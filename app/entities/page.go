@@ -0,0 +1,87 @@
+package entities
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	SortById        = "id"
+	SortByName      = "name"
+	SortByUrl       = "url"
+	SortByStatus    = "status"
+	SortByCreatedAt = "createdAt"
+)
+
+// PageRequest asks for the entities following the one a cursor was made from,
+// in the order of SortBy.
+type PageRequest struct {
+	Limit      int
+	Cursor     *Cursor
+	SortBy     string
+	Descending bool
+}
+
+// Cursor points at the last entity of a page by its sort key and id, which
+// breaks ties between entities with the same key.
+type Cursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Key        string `json:"k"`
+	Id         string `json:"i"`
+}
+
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"nextCursor,omitempty"`
+	Total      int     `json:"total"`
+}
+
+type ResourcePage struct {
+	Page[Resource]
+	// StatusCounts counts every resource of the domain by status, regardless
+	// of the filter.
+	StatusCounts map[string]int `json:"statusCounts"`
+}
+
+type DomainFilter struct {
+	// Ids limits the domains to the given ones when it is not nil.
+	Ids    []string
+	Search string
+}
+
+type ResourceFilter struct {
+	DomainId       string
+	Statuses       []string
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	IngestedAfter  *time.Time
+	IngestedBefore *time.Time
+	// Search matches a substring of the name or url.
+	Search string
+}
+
+func (cursor Cursor) Encode() string {
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor: %w", err)
+	}
+
+	cursor := &Cursor{}
+
+	if err = json.Unmarshal(data, cursor); err != nil {
+		return nil, fmt.Errorf("malformed cursor: %w", err)
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,62 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Patch is a field of a JSON merge patch. Set tells a field left out of the
+// patch apart from one set to null, in which case Value is nil.
+type Patch[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (patch *Patch[T]) UnmarshalJSON(data []byte) error {
+
+	patch.Set = true
+
+	if string(data) == "null" {
+		patch.Value = nil
+		return nil
+	}
+
+	return json.Unmarshal(data, &patch.Value)
+}
+
+// Apply replaces target with the patched value, or the zero value when the
+// patch removes it.
+func (patch Patch[T]) Apply(target *T) {
+
+	if !patch.Set {
+		return
+	}
+
+	if patch.Value == nil {
+		var zero T
+		*target = zero
+		return
+	}
+
+	*target = *patch.Value
+}
+
+// ApplyOptional replaces an optional target with the patched value, or nil
+// when the patch removes it.
+func (patch Patch[T]) ApplyOptional(target **T) {
+
+	if patch.Set {
+		*target = patch.Value
+	}
+}
+
+// Version is the time an entity was last changed, used to detect concurrent
+// updates.
+func Version(createdAt time.Time, updatedAt *time.Time) time.Time {
+
+	if updatedAt != nil {
+		return *updatedAt
+	}
+
+	return createdAt
+}
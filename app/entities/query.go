@@ -1,7 +1,34 @@
 package entities
 
+import "encoding/json"
+
+// AllDomains can be passed as the only domain id of a query to search every domain.
+const AllDomains = "*"
+
 type Query struct {
-	Question string
-	DomainId string
-	Concepts []Concept
+	Question  string    `json:"Question"`
+	DomainIds []string  `json:"DomainIds"`
+	Concepts  []Concept `json:"Concepts"`
+	// Model overrides the LLM model used to answer, when set.
+	Model string `json:"-"`
+	// History holds the earlier messages of a conversation the question is a follow-up to.
+	History []Message `json:"-"`
+}
+
+// MarshalJSON adds the DomainId field that queries had before they could
+// search several domains, for queries of a single domain.
+func (query Query) MarshalJSON() ([]byte, error) {
+
+	type fields Query
+
+	body := struct {
+		fields
+		DomainId string `json:"DomainId,omitempty"`
+	}{fields: fields(query)}
+
+	if len(query.DomainIds) == 1 {
+		body.DomainId = query.DomainIds[0]
+	}
+
+	return json.Marshal(body)
 }
@@ -8,17 +8,44 @@ const (
 	ResourceStatusNew       = "NEW"
 	ResourceStatusIngesting = "INGESTING"
 	ResourceStatusIngested  = "INGESTED"
+	ResourceStatusFailed    = "FAILED"
 )
 
 type Resource struct {
-	Id                   int        `json:"id"`
-	DomainId             string     `json:"domainId"`
-	Name                 string     `json:"name"`
-	Description          string     `json:"description"`
-	Status               string     `json:"status"`
-	Url                  string     `json:"url"`
-	CreatedAt            time.Time  `json:"createdAt"`
-	UpdatedAt            *time.Time `json:"updatedAt,omitempty"`
-	IngestionStartedAt   *time.Time `json:"ingestion_started_at,omitempty"`
-	IngestionCompletedAt *time.Time `json:"ingestion_completed_at,omitempty"`
+	Id                     int        `json:"id"`
+	DomainId               string     `json:"domainId"`
+	Name                   string     `json:"name"`
+	Description            string     `json:"description"`
+	Status                 string     `json:"status"`
+	Url                    string     `json:"url"`
+	CreatedAt              time.Time  `json:"createdAt"`
+	UpdatedAt              *time.Time `json:"updatedAt,omitempty"`
+	IngestionStartedAt     *time.Time `json:"ingestion_started_at,omitempty"`
+	IngestionCompletedAt   *time.Time `json:"ingestion_completed_at,omitempty"`
+	LastError              *string    `json:"lastError,omitempty"`
+	Attempts               int        `json:"attempts"`
+	NextAttemptAt          *time.Time `json:"nextAttemptAt,omitempty"`
+	RefreshIntervalSeconds *int       `json:"refreshIntervalSeconds,omitempty"`
+	ContentHash            *string    `json:"contentHash,omitempty"`
+}
+
+// ResourcePatch holds the fields of a resource that can be changed.
+type ResourcePatch struct {
+	Name                   Patch[string] `json:"name"`
+	Description            Patch[string] `json:"description"`
+	Url                    Patch[string] `json:"url"`
+	RefreshIntervalSeconds Patch[int]    `json:"refreshIntervalSeconds"`
+}
+
+func (resource Resource) Version() time.Time {
+
+	return Version(resource.CreatedAt, resource.UpdatedAt)
+}
+
+func (patch ResourcePatch) Apply(resource *Resource) {
+
+	patch.Name.Apply(&resource.Name)
+	patch.Description.Apply(&resource.Description)
+	patch.Url.Apply(&resource.Url)
+	patch.RefreshIntervalSeconds.ApplyOptional(&resource.RefreshIntervalSeconds)
 }
@@ -1,7 +1,24 @@
 package entities
 
+const (
+	ResponseEventConcepts = "concepts"
+	ResponseEventSources  = "sources"
+	ResponseEventToken    = "token"
+	ResponseEventDone     = "done"
+)
+
 type Response struct {
-	Query    Query    `json:"query"`
-	Response string   `json:"response"`
-	Sources  []string `json:"sources"`
+	Query    Query      `json:"query"`
+	Response string     `json:"response"`
+	Sources  []Citation `json:"sources"`
+}
+
+type ResponseEvent struct {
+	Type     string     `json:"type"`
+	Concepts []Concept  `json:"concepts,omitempty"`
+	Sources  []Citation `json:"sources,omitempty"`
+	Token    string     `json:"token,omitempty"`
+	Response *Response  `json:"response,omitempty"`
 }
+
+type ResponseEventHandler func(ResponseEvent) error
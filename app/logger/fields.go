@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"context"
+	"maps"
+)
+
+const (
+	FieldRequestId      = "requestId"
+	FieldDomainId       = "domainId"
+	FieldResourceId     = "resourceId"
+	FieldConversationId = "conversationId"
+	FieldCaller         = "caller"
+)
+
+// Fields are added to every entry logged with a context carrying them.
+type Fields map[string]any
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx whose log entries carry fields on top of
+// the ones ctx already has. Later values replace earlier ones of the same key.
+func WithFields(ctx context.Context, fields Fields) context.Context {
+
+	merged := maps.Clone(FieldsFromContext(ctx))
+
+	if merged == nil {
+		merged = make(Fields, len(fields))
+	}
+
+	maps.Copy(merged, fields)
+
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FieldsFromContext returns the fields added to ctx. They must not be modified.
+func FieldsFromContext(ctx context.Context) Fields {
+
+	fields, _ := ctx.Value(fieldsKey{}).(Fields)
+	return fields
+}
@@ -15,7 +15,9 @@ type Logger interface {
 	Debug(context.Context, string)
 	Warn(context.Context, string)
 	Error(context.Context, string)
-	SetLevel(string)
+	// SetLevel takes one of TRACE, DEBUG, INFO, WARN and ERROR.
+	SetLevel(string) error
+	Level() string
 }
 
 func Instance() Logger {
@@ -2,53 +2,98 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
+	"sync/atomic"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// callerSkipFrames points the caller of an entry past the methods of zeroLogger.
+const callerSkipFrames = 2
+
 type zeroLogger struct {
 	instance zerolog.Logger
+	level    *atomic.Int32
 }
 
 func NewZeroLogger() Logger {
 
-	instance := zerolog.New(os.Stdout)
-	return &zeroLogger{instance}
+	instance := zerolog.New(os.Stdout).With().Timestamp().Logger()
+
+	level := &atomic.Int32{}
+	level.Store(int32(zerolog.InfoLevel))
+
+	return &zeroLogger{instance, level}
 }
 
 func (logger *zeroLogger) Info(ctx context.Context, message string) {
 
-	logger.instance.Info().Msg(message)
+	logger.log(ctx, zerolog.InfoLevel, message)
 }
 
 func (logger *zeroLogger) Debug(ctx context.Context, message string) {
 
-	logger.instance.Debug().Msg(message)
+	logger.log(ctx, zerolog.DebugLevel, message)
 }
 
 func (logger *zeroLogger) Warn(ctx context.Context, message string) {
 
-	logger.instance.Warn().Msg(message)
+	logger.log(ctx, zerolog.WarnLevel, message)
 }
 
 func (logger *zeroLogger) Error(ctx context.Context, message string) {
 
-	logger.instance.Error().Msg(message)
+	logger.log(ctx, zerolog.ErrorLevel, message)
+}
+
+// SetLevel may be called while other goroutines are logging.
+func (logger *zeroLogger) SetLevel(level string) error {
+
+	parsed, err := zerolog.ParseLevel(strings.ToLower(level))
+
+	if err != nil || !validLevel(parsed) {
+		return fmt.Errorf("unknown log level %s", level)
+	}
+
+	logger.level.Store(int32(parsed))
+
+	return nil
+}
+
+func (logger *zeroLogger) Level() string {
+
+	return strings.ToUpper(zerolog.Level(logger.level.Load()).String())
 }
 
-func (logger *zeroLogger) SetLevel(level string) {
+func (logger *zeroLogger) log(ctx context.Context, level zerolog.Level, message string) {
 
-	switch level {
-	case "DEBUG":
-		logger.instance.Level(zerolog.DebugLevel)
-	case "INFO":
-		logger.instance.Level(zerolog.InfoLevel)
-	case "TRACE":
-		logger.instance.Level(zerolog.TraceLevel)
-	case "ERROR":
-		logger.instance.Level(zerolog.ErrorLevel)
-	case "WARN":
-		logger.instance.Level(zerolog.WarnLevel)
+	if level < zerolog.Level(logger.level.Load()) {
+		return
 	}
+
+	event := logger.instance.WithLevel(level).CallerSkipFrame(callerSkipFrames).Caller()
+
+	if fields := FieldsFromContext(ctx); len(fields) > 0 {
+		event = event.Fields(map[string]any(fields))
+	}
+
+	withTrace(ctx, event).Msg(message)
+}
+
+func validLevel(level zerolog.Level) bool {
+
+	return level >= zerolog.TraceLevel && level <= zerolog.ErrorLevel
+}
+
+// withTrace tags the entry with the trace and span it was logged in, if any.
+func withTrace(ctx context.Context, event *zerolog.Event) *zerolog.Event {
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		return event.Str("traceId", spanContext.TraceID().String()).Str("spanId", spanContext.SpanID().String())
+	}
+
+	return event
 }
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+)
+
+const queueDepthTimeout = 5 * time.Second
+
+var (
+	poolAcquiredConns = prometheus.NewDesc(namespace+"_pgxpool_acquired_conns", "Connections currently in use.", nil, nil)
+	poolIdleConns     = prometheus.NewDesc(namespace+"_pgxpool_idle_conns", "Idle connections in the pool.", nil, nil)
+	poolTotalConns    = prometheus.NewDesc(namespace+"_pgxpool_total_conns", "Connections in the pool.", nil, nil)
+	poolMaxConns      = prometheus.NewDesc(namespace+"_pgxpool_max_conns", "Maximum size of the pool.", nil, nil)
+	poolAcquireCount  = prometheus.NewDesc(namespace+"_pgxpool_acquire_total", "Connections acquired from the pool.", nil, nil)
+	poolAcquireWait   = prometheus.NewDesc(namespace+"_pgxpool_acquire_wait_seconds_total", "Time spent waiting for a connection.", nil, nil)
+	poolEmptyAcquire  = prometheus.NewDesc(namespace+"_pgxpool_empty_acquire_total", "Acquires that had to wait for a connection.", nil, nil)
+	queueDepth        = prometheus.NewDesc(namespace+"_ingestion_queue_depth", "Resources by ingestion status.", []string{"status"}, nil)
+)
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+
+	return &poolCollector{pool}
+}
+
+func (collector *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+
+	ch <- poolAcquiredConns
+	ch <- poolIdleConns
+	ch <- poolTotalConns
+	ch <- poolMaxConns
+	ch <- poolAcquireCount
+	ch <- poolAcquireWait
+	ch <- poolEmptyAcquire
+}
+
+func (collector *poolCollector) Collect(ch chan<- prometheus.Metric) {
+
+	stat := collector.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+}
+
+type queueDepthCollector struct {
+	resourceRepo repos.ResourceRepo
+}
+
+// NewQueueDepthCollector counts resources by status on every scrape. Statuses
+// without resources are reported as 0 so that their series do not disappear.
+func NewQueueDepthCollector(resourceRepo repos.ResourceRepo) prometheus.Collector {
+
+	return &queueDepthCollector{resourceRepo}
+}
+
+func (collector *queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+
+	ch <- queueDepth
+}
+
+func (collector *queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), queueDepthTimeout)
+	defer cancel()
+
+	counts, err := collector.resourceRepo.CountByStatus(ctx)
+
+	if err != nil {
+		logger.Instance().Error(ctx, err.Error())
+		ch <- prometheus.NewInvalidMetric(queueDepth, err)
+		return
+	}
+
+	for _, status := range []string{entities.ResourceStatusNew, entities.ResourceStatusIngesting, entities.ResourceStatusIngested, entities.ResourceStatusFailed} {
+		ch <- prometheus.MustNewConstMetric(queueDepth, prometheus.GaugeValue, float64(counts[status]), status)
+	}
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/utsavgupta/knowledge-hub/app/uc"
+)
+
+const (
+	namespace = "kh"
+
+	OutcomeSuccess           = "success"
+	OutcomeValidationFailure = "validation_failure"
+	OutcomeRejected          = "rejected"
+	OutcomeUpstreamFailure   = "upstream_failure"
+)
+
+type Metrics struct {
+	registry         *prometheus.Registry
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	ucCalls          *prometheus.CounterVec
+	ucDuration       *prometheus.HistogramVec
+	upstreamCalls    *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+}
+
+func NewMetrics() *Metrics {
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	m := &Metrics{
+		registry: registry,
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "http_requests_total", Help: "HTTP requests served by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "http_request_duration_seconds", Help: "Latency of HTTP requests by route, method and status.", Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		ucCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "uc_calls_total", Help: "Use case calls by use case and outcome.",
+		}, []string{"uc", "outcome"}),
+		ucDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "uc_call_duration_seconds", Help: "Latency of use case calls by use case and outcome.", Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"uc", "outcome"}),
+		upstreamCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "upstream_calls_total", Help: "Calls to upstream services by upstream, operation and outcome.",
+		}, []string{"upstream", "operation", "outcome"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "upstream_call_duration_seconds", Help: "Latency of calls to upstream services by upstream, operation and outcome.", Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"upstream", "operation", "outcome"}),
+	}
+
+	registry.MustRegister(m.httpRequests, m.httpDuration, m.ucCalls, m.ucDuration, m.upstreamCalls, m.upstreamDuration)
+
+	return m
+}
+
+// Register adds collectors that are read on every scrape, such as the pool
+// statistics and the ingestion queue depth.
+func (m *Metrics) Register(collectors ...prometheus.Collector) {
+
+	m.registry.MustRegister(collectors...)
+}
+
+func (m *Metrics) Handler() http.Handler {
+
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) observeUc(name string, start time.Time, err error) {
+
+	outcome := ucOutcome(err)
+
+	m.ucCalls.WithLabelValues(name, outcome).Inc()
+	m.ucDuration.WithLabelValues(name, outcome).Observe(time.Since(start).Seconds())
+}
+
+func (m *Metrics) observeUpstream(upstream string, operation string, start time.Time, err error) {
+
+	outcome := OutcomeSuccess
+
+	if err != nil {
+		outcome = OutcomeUpstreamFailure
+	}
+
+	m.upstreamCalls.WithLabelValues(upstream, operation, outcome).Inc()
+	m.upstreamDuration.WithLabelValues(upstream, operation, outcome).Observe(time.Since(start).Seconds())
+}
+
+func ucOutcome(err error) string {
+
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, uc.ValidationError), errors.Is(err, uc.NotFoundError):
+		return OutcomeValidationFailure
+	case errors.Is(err, uc.ForbiddenError), errors.Is(err, uc.UnauthenticatedError), errors.Is(err, uc.LimitExceededError),
+		errors.Is(err, uc.ConflictError), errors.Is(err, uc.PreconditionFailedError):
+		return OutcomeRejected
+	}
+
+	return OutcomeUpstreamFailure
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
+
+type instrumentedCitationRepo struct {
+	m        *Metrics
+	upstream string
+	next     repos.CitationRepo
+}
+
+// InstrumentCitationRepo measures retrieval from a vector store under its
+// name, e.g. weaviate or pgvector. Generating the answer is measured by the
+// LLM client.
+func (m *Metrics) InstrumentCitationRepo(upstream string, next repos.CitationRepo) repos.CitationRepo {
+
+	return &instrumentedCitationRepo{m, upstream, next}
+}
+
+func (repo *instrumentedCitationRepo) Retrieve(ctx context.Context, query entities.Query) (citations []entities.Citation, err error) {
+
+	start := time.Now()
+	defer func() { repo.m.observeUpstream(repo.upstream, "retrieve", start, err) }()
+
+	return repo.next.Retrieve(ctx, query)
+}
+
+type instrumentedChunkRepo struct {
+	m        *Metrics
+	upstream string
+	next     repos.ChunkRepo
+}
+
+func (m *Metrics) InstrumentChunkRepo(upstream string, next repos.ChunkRepo) repos.ChunkRepo {
+
+	return &instrumentedChunkRepo{m, upstream, next}
+}
+
+func (repo *instrumentedChunkRepo) Replace(ctx context.Context, domainId string, resourceId int, chunks []entities.Chunk) (err error) {
+
+	start := time.Now()
+	defer func() { repo.m.observeUpstream(repo.upstream, "replace_chunks", start, err) }()
+
+	return repo.next.Replace(ctx, domainId, resourceId, chunks)
+}
+
+func (repo *instrumentedChunkRepo) CreateCollection(ctx context.Context, domainId string) (err error) {
+
+	start := time.Now()
+	defer func() { repo.m.observeUpstream(repo.upstream, "create_collection", start, err) }()
+
+	return repo.next.CreateCollection(ctx, domainId)
+}
+
+func (repo *instrumentedChunkRepo) DeleteCollection(ctx context.Context, domainId string) (err error) {
+
+	start := time.Now()
+	defer func() { repo.m.observeUpstream(repo.upstream, "delete_collection", start, err) }()
+
+	return repo.next.DeleteCollection(ctx, domainId)
+}
+
+func (repo *instrumentedChunkRepo) DeleteByResource(ctx context.Context, domainId string, resourceId int) (err error) {
+
+	start := time.Now()
+	defer func() { repo.m.observeUpstream(repo.upstream, "delete_chunks", start, err) }()
+
+	return repo.next.DeleteByResource(ctx, domainId, resourceId)
+}
+
+type instrumentedLLMClient struct {
+	m    *Metrics
+	next services.LLMClient
+}
+
+func (m *Metrics) InstrumentLLMClient(next services.LLMClient) services.LLMClient {
+
+	return &instrumentedLLMClient{m, next}
+}
+
+func (llm *instrumentedLLMClient) Complete(ctx context.Context, request services.CompletionRequest) (answer string, err error) {
+
+	start := time.Now()
+	defer func() { llm.m.observeUpstream("openai", "chat_complete", start, err) }()
+
+	return llm.next.Complete(ctx, request)
+}
+
+func (llm *instrumentedLLMClient) Stream(ctx context.Context, request services.CompletionRequest, onToken func(string) error) (answer string, err error) {
+
+	start := time.Now()
+	defer func() { llm.m.observeUpstream("openai", "chat_stream", start, err) }()
+
+	return llm.next.Stream(ctx, request, onToken)
+}
+
+type instrumentedEmbeddingService struct {
+	m    *Metrics
+	next services.EmbeddingService
+}
+
+func (m *Metrics) InstrumentEmbeddingService(next services.EmbeddingService) services.EmbeddingService {
+
+	return &instrumentedEmbeddingService{m, next}
+}
+
+func (service *instrumentedEmbeddingService) Get(ctx context.Context, texts []string) (embeddings [][]float32, err error) {
+
+	start := time.Now()
+	defer func() { service.m.observeUpstream("openai", "embed", start, err) }()
+
+	return service.next.Get(ctx, texts)
+}
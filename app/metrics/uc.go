@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/uc"
+)
+
+func (m *Metrics) InstrumentSearchUc(next uc.SearchUc) uc.SearchUc {
+
+	return func(ctx context.Context, query entities.Query) (response *entities.Response, err error) {
+
+		start := time.Now()
+		defer func() { m.observeUc("search", start, err) }()
+
+		return next(ctx, query)
+	}
+}
+
+func (m *Metrics) InstrumentStreamSearchUc(next uc.StreamSearchUc) uc.StreamSearchUc {
+
+	return func(ctx context.Context, query entities.Query, handler entities.ResponseEventHandler) (response *entities.Response, err error) {
+
+		start := time.Now()
+		defer func() { m.observeUc("stream_search", start, err) }()
+
+		return next(ctx, query, handler)
+	}
+}
+
+func (m *Metrics) InstrumentSendMessageUc(next uc.SendMessageUc) uc.SendMessageUc {
+
+	return func(ctx context.Context, id int, question string) (message *entities.Message, err error) {
+
+		start := time.Now()
+		defer func() { m.observeUc("send_message", start, err) }()
+
+		return next(ctx, id, question)
+	}
+}
@@ -0,0 +1,9 @@
+-- IF NOT EXISTS adopts databases whose tables were created by hand before
+-- migrations were introduced.
+CREATE TABLE IF NOT EXISTS domains (
+    id          VARCHAR(15) PRIMARY KEY,
+    name        VARCHAR(50) NOT NULL,
+    description VARCHAR(140) NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ
+);
@@ -0,0 +1,20 @@
+-- IF NOT EXISTS adopts databases whose tables were created by hand before
+-- migrations were introduced.
+CREATE TABLE IF NOT EXISTS resources (
+    id                     SERIAL PRIMARY KEY,
+    domain_id              VARCHAR(15) NOT NULL REFERENCES domains (id) ON DELETE CASCADE,
+    name                   VARCHAR(50) NOT NULL DEFAULT '',
+    description            VARCHAR(140) NOT NULL DEFAULT '',
+    status                 VARCHAR(16) NOT NULL DEFAULT 'NEW',
+    url                    TEXT NOT NULL,
+    created_at             TIMESTAMPTZ NOT NULL,
+    updated_at             TIMESTAMPTZ,
+    ingestion_started_at   TIMESTAMPTZ,
+    ingestion_completed_at TIMESTAMPTZ,
+    last_error             TEXT,
+    attempts               INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at        TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS resources_domain_id_idx ON resources (domain_id);
+CREATE INDEX IF NOT EXISTS resources_status_idx ON resources (status);
@@ -0,0 +1,17 @@
+-- requires: pgvector
+-- Chunks are only stored in Postgres when kh_vector_store is pgvector, so the
+-- migration is left pending otherwise. It fails on servers that do not have
+-- the vector extension installed.
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS chunks (
+    id          BIGSERIAL PRIMARY KEY,
+    domain_id   VARCHAR(15) NOT NULL REFERENCES domains (id) ON DELETE CASCADE,
+    resource_id INTEGER NOT NULL REFERENCES resources (id) ON DELETE CASCADE,
+    text        TEXT NOT NULL,
+    source      TEXT NOT NULL,
+    embedding   vector(1536) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS chunks_domain_id_idx ON chunks (domain_id);
+CREATE INDEX IF NOT EXISTS chunks_resource_id_idx ON chunks (resource_id);
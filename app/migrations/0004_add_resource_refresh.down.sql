@@ -0,0 +1,3 @@
+ALTER TABLE resources
+    DROP COLUMN refresh_interval_seconds,
+    DROP COLUMN content_hash;
@@ -0,0 +1,3 @@
+ALTER TABLE resources
+    ADD COLUMN refresh_interval_seconds INTEGER,
+    ADD COLUMN content_hash             TEXT;
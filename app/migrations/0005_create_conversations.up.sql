@@ -0,0 +1,19 @@
+CREATE TABLE conversations (
+    id         SERIAL PRIMARY KEY,
+    domain_id  VARCHAR(15) NOT NULL REFERENCES domains (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX conversations_domain_id_idx ON conversations (domain_id);
+
+CREATE TABLE conversation_messages (
+    id              SERIAL PRIMARY KEY,
+    conversation_id INTEGER NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+    role            VARCHAR(16) NOT NULL,
+    content         TEXT NOT NULL,
+    sources         JSONB NOT NULL DEFAULT '[]',
+    created_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX conversation_messages_conversation_id_idx ON conversation_messages (conversation_id);
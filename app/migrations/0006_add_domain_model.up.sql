@@ -0,0 +1 @@
+ALTER TABLE domains ADD COLUMN model VARCHAR(100);
@@ -0,0 +1,10 @@
+CREATE TABLE api_keys (
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(50) NOT NULL,
+    role       VARCHAR(16) NOT NULL,
+    domain_ids TEXT[] NOT NULL DEFAULT '{}',
+    prefix     VARCHAR(16) NOT NULL,
+    key_hash   CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
@@ -0,0 +1,6 @@
+DROP TABLE llm_usage;
+DROP TABLE rate_limit_buckets;
+
+ALTER TABLE domains
+    DROP COLUMN daily_token_budget,
+    DROP COLUMN monthly_token_budget;
@@ -0,0 +1,16 @@
+ALTER TABLE domains
+    ADD COLUMN daily_token_budget   INTEGER,
+    ADD COLUMN monthly_token_budget INTEGER;
+
+CREATE TABLE rate_limit_buckets (
+    key        TEXT PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE llm_usage (
+    domain_id VARCHAR(15) NOT NULL REFERENCES domains (id) ON DELETE CASCADE,
+    day       DATE NOT NULL,
+    tokens    BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (domain_id, day)
+);
@@ -0,0 +1,2 @@
+-- Nothing to revert, 0003 is recorded again when it is applied.
+SELECT 1;
@@ -0,0 +1,4 @@
+-- 0003 used to be recorded as applied on servers without the vector extension
+-- even though it created nothing. It is forgotten there, so that it is
+-- applied once the pgvector store is enabled.
+DELETE FROM schema_migrations WHERE version = 3 AND to_regclass('chunks') IS NULL;
@@ -0,0 +1,323 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationsLockId is an arbitrary key for the Postgres advisory lock that
+// stops two processes from migrating the same database at once.
+const migrationsLockId = 7_263_904_117
+
+// requiresPrefix starts the first line of an up file that only applies when
+// a feature is enabled, e.g. -- requires: pgvector.
+const requiresPrefix = "-- requires:"
+
+//go:embed *.sql
+var files embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// Requires names the feature the migration is for. It is left pending
+	// until the feature is enabled.
+	Requires string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator interface {
+	Up(context.Context) ([]Migration, error)
+	Down(context.Context, int) ([]Migration, error)
+	Status(context.Context) ([]MigrationStatus, error)
+}
+
+type pgMigrator struct {
+	conn       *pgxpool.Pool
+	migrations []Migration
+	features   []string
+}
+
+// NewPGMigrator applies the embedded migrations, leaving out those that
+// require a feature other than the given ones.
+func NewPGMigrator(connPool *pgxpool.Pool, features ...string) (Migrator, error) {
+
+	migrations, err := loadMigrations(files)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &pgMigrator{connPool, migrations, features}, nil
+}
+
+// Up applies every pending migration in order and returns the ones it applied.
+// The applied migrations are listed again after each of them, since a
+// migration may forget an earlier one so that it is applied again.
+func (migrator *pgMigrator) Up(ctx context.Context) ([]Migration, error) {
+
+	applied := make([]Migration, 0)
+
+	err := migrator.withLock(ctx, func(conn *pgxpool.Conn, appliedAt map[int]time.Time) error {
+
+		for {
+
+			migration, ok := migrator.nextPending(appliedAt)
+
+			if !ok {
+				return nil
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", migration.Version, migration.Name, time.Now())
+				return err
+			})
+
+			if err != nil {
+				return fmt.Errorf("could not apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			applied = append(applied, migration)
+
+			if appliedAt, err = migrator.listApplied(ctx, conn); err != nil {
+				return err
+			}
+		}
+	})
+
+	return applied, err
+}
+
+func (migrator *pgMigrator) nextPending(appliedAt map[int]time.Time) (Migration, bool) {
+
+	for _, migration := range migrator.migrations {
+
+		if _, ok := appliedAt[migration.Version]; ok {
+			continue
+		}
+
+		if len(migration.Requires) > 0 && !slices.Contains(migrator.features, migration.Requires) {
+			continue
+		}
+
+		return migration, true
+	}
+
+	return Migration{}, false
+}
+
+// Down reverts the latest steps applied migrations and returns the ones it
+// reverted.
+func (migrator *pgMigrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+
+	reverted := make([]Migration, 0, steps)
+
+	err := migrator.withLock(ctx, func(conn *pgxpool.Conn, appliedAt map[int]time.Time) error {
+
+		for i := len(migrator.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+
+			migration := migrator.migrations[i]
+
+			if _, ok := appliedAt[migration.Version]; !ok {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			})
+
+			if err != nil {
+				return fmt.Errorf("could not revert migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+func (migrator *pgMigrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+
+	statuses := make([]MigrationStatus, 0, len(migrator.migrations))
+
+	err := migrator.withLock(ctx, func(conn *pgxpool.Conn, appliedAt map[int]time.Time) error {
+
+		for _, migration := range migrator.migrations {
+
+			status := MigrationStatus{Migration: migration}
+
+			if t, ok := appliedAt[migration.Version]; ok {
+				status.AppliedAt = &t
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+func (migrator *pgMigrator) withLock(ctx context.Context, fn func(*pgxpool.Conn, map[int]time.Time) error) error {
+
+	conn, err := migrator.conn.Acquire(ctx)
+
+	if err != nil {
+		return fmt.Errorf("could not acquire connection for migrations: %w", err)
+	}
+
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationsLockId); err != nil {
+		return fmt.Errorf("could not lock migrations: %w", err)
+	}
+
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockId)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`)
+
+	if err != nil {
+		return fmt.Errorf("could not create schema_migrations table: %w", err)
+	}
+
+	appliedAt, err := migrator.listApplied(ctx, conn)
+
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, appliedAt)
+}
+
+func (migrator *pgMigrator) listApplied(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+
+	row, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+
+	if err != nil {
+		return nil, fmt.Errorf("could not list applied migrations: %w", err)
+	}
+
+	defer row.Close()
+
+	appliedAt := make(map[int]time.Time)
+
+	for row.Next() {
+
+		var version int
+		var t time.Time
+
+		if err = row.Scan(&version, &t); err != nil {
+			return nil, fmt.Errorf("could not read applied migration: %w", err)
+		}
+
+		appliedAt[version] = t
+	}
+
+	return appliedAt, row.Err()
+}
+
+// loadMigrations pairs the embedded <version>_<name>.up.sql and
+// <version>_<name>.down.sql files and sorts them by version.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+
+	names, err := fs.Glob(fsys, "*.sql")
+
+	if err != nil {
+		return nil, fmt.Errorf("could not list migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, fileName := range names {
+
+		base, direction, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s should be named <version>_<name>.up.sql or <version>_<name>.down.sql", fileName)
+		}
+
+		prefix, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+
+		if err != nil {
+			return nil, fmt.Errorf("migration %s does not start with a version number", fileName)
+		}
+
+		b, err := fs.ReadFile(fsys, fileName)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read migration %s: %w", fileName, err)
+		}
+
+		migration, ok := byVersion[version]
+
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, name)
+		}
+
+		if direction == "up" {
+			migration.Up = string(b)
+			firstLine, _, _ := strings.Cut(migration.Up, "\n")
+
+			if requires, ok := strings.CutPrefix(firstLine, requiresPrefix); ok {
+				migration.Requires = strings.TrimSpace(requires)
+			}
+		} else {
+			migration.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+
+		if len(migration.Up) < 1 || len(migration.Down) < 1 {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
@@ -2,6 +2,7 @@ package repos
 
 import (
 	"context"
+	"errors"
 
 	"github.com/utsavgupta/knowledge-hub/app/entities"
 )
@@ -9,3 +10,18 @@ import (
 type ResponseRepo interface {
 	Get(context.Context, entities.Query) (*entities.Response, error)
 }
+
+type StreamingResponseRepo interface {
+	ResponseRepo
+	Stream(context.Context, entities.Query, entities.ResponseEventHandler) (*entities.Response, error)
+}
+
+// NoCitationsError is returned by a CitationRepo when nothing in the vector
+// store relates to a query.
+var NoCitationsError = errors.New("no citations found")
+
+// CitationRepo retrieves the chunks of the vector store that are closest to a
+// query.
+type CitationRepo interface {
+	Retrieve(context.Context, entities.Query) ([]entities.Citation, error)
+}
@@ -0,0 +1,15 @@
+package repos
+
+import (
+	"context"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+)
+
+type ApiKeyRepo interface {
+	List(context.Context) ([]entities.ApiKey, error)
+	GetByHash(context.Context, string) (*entities.ApiKey, error)
+	Create(context.Context, entities.ApiKey) (*entities.ApiKey, error)
+	Revoke(context.Context, int, time.Time) (bool, error)
+}
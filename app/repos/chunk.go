@@ -0,0 +1,14 @@
+package repos
+
+import (
+	"context"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+)
+
+type ChunkRepo interface {
+	Replace(context.Context, string, int, []entities.Chunk) error
+	CreateCollection(context.Context, string) error
+	DeleteCollection(context.Context, string) error
+	DeleteByResource(context.Context, string, int) error
+}
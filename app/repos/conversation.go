@@ -0,0 +1,13 @@
+package repos
+
+import (
+	"context"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+)
+
+type ConversationRepo interface {
+	Get(context.Context, int) (*entities.Conversation, error)
+	Create(context.Context, entities.Conversation) (*entities.Conversation, error)
+	AddMessages(context.Context, int, []entities.Message) ([]entities.Message, error)
+}
@@ -2,14 +2,18 @@ package repos
 
 import (
 	"context"
+	"time"
 
 	"github.com/utsavgupta/knowledge-hub/app/entities"
 )
 
 type DomainRepo interface {
 	List(context.Context) ([]entities.Domain, error)
+	Find(context.Context, entities.DomainFilter, entities.PageRequest) (*entities.Page[entities.Domain], error)
 	Get(context.Context, string) (*entities.Domain, error)
 	Create(context.Context, entities.Domain) (*entities.Domain, error)
-	Update(context.Context, entities.Domain) (*entities.Domain, error)
+	// Update only changes the domain if it is still at the given version, and
+	// returns nil otherwise.
+	Update(context.Context, entities.Domain, time.Time) (*entities.Domain, error)
 	Delete(context.Context, string) error
 }
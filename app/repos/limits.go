@@ -0,0 +1,22 @@
+package repos
+
+import (
+	"context"
+	"time"
+)
+
+type RateLimitRepo interface {
+	// Take removes a token from the bucket of the key, which holds at most
+	// capacity tokens and regains refillPerSecond tokens every second. It
+	// returns how long to wait for a token when the bucket is empty.
+	Take(ctx context.Context, key string, capacity float64, refillPerSecond float64, now time.Time) (time.Duration, error)
+	// DeleteIdle removes the buckets last used before the given time and
+	// returns how many were removed.
+	DeleteIdle(ctx context.Context, before time.Time) (int, error)
+}
+
+type BudgetRepo interface {
+	// Usage returns the tokens a domain has spent on the day and in the month of now.
+	Usage(ctx context.Context, domainId string, now time.Time) (int, int, error)
+	Record(ctx context.Context, domainId string, tokens int, now time.Time) error
+}
@@ -2,14 +2,26 @@ package repos
 
 import (
 	"context"
+	"time"
 
 	"github.com/utsavgupta/knowledge-hub/app/entities"
 )
 
 type ResourceRepo interface {
 	List(context.Context, string) ([]entities.Resource, error)
+	Find(context.Context, entities.ResourceFilter, entities.PageRequest) (*entities.ResourcePage, error)
 	Get(context.Context, int) (*entities.Resource, error)
 	Create(context.Context, entities.Resource) (*entities.Resource, error)
-	Update(context.Context, entities.Resource) (*entities.Resource, error)
+	// Update only changes the resource if it is still at the given version,
+	// and returns nil otherwise.
+	Update(context.Context, entities.Resource, time.Time) (*entities.Resource, error)
 	Delete(context.Context, string, int) error
+	ClaimNew(context.Context, time.Time) (*entities.Resource, error)
+	UpdateIngestionStatus(context.Context, entities.Resource) (*entities.Resource, error)
+	// Requeue marks a resource of the domain as new unless it is being
+	// ingested, and returns nil when it is or when it does not exist.
+	Requeue(ctx context.Context, domainId string, id int, resetContentHash bool, now time.Time) (*entities.Resource, error)
+	ReleaseStale(ctx context.Context, startedBefore time.Time, retryAt time.Time, maxAttempts int) (int, error)
+	RequeueDue(context.Context, time.Time) (int, error)
+	CountByStatus(context.Context) (map[string]int, error)
 }
@@ -0,0 +1,111 @@
+package runners
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/uc"
+)
+
+type ingestionWorker struct {
+	concurrency  int
+	pollInterval time.Duration
+	drainTimeout time.Duration
+	ingestUc     uc.IngestNextResourceUc
+	releaseUc    uc.ReleaseStaleResourcesUc
+}
+
+func NewIngestionWorker(concurrency int, pollInterval time.Duration, drainTimeout time.Duration, ingestUc uc.IngestNextResourceUc, releaseUc uc.ReleaseStaleResourcesUc) Runner {
+
+	return &ingestionWorker{concurrency, pollInterval, drainTimeout, ingestUc, releaseUc}
+}
+
+// Run stops polling once ctx is cancelled. Resources being ingested at that
+// point are given up to the drain timeout to finish, after which they are
+// interrupted and returned to the queue.
+func (worker ingestionWorker) Run(ctx context.Context) error {
+
+	logger.Instance().Info(ctx, fmt.Sprintf("Starting ingestion worker with concurrency %d", worker.concurrency))
+
+	drainCtx, cancelDrain := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelDrain()
+
+	stopDrain := context.AfterFunc(ctx, func() {
+		time.AfterFunc(worker.drainTimeout, cancelDrain)
+	})
+	defer stopDrain()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < worker.concurrency; i++ {
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			worker.poll(ctx, drainCtx)
+		}()
+	}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		worker.releaseStale(ctx)
+	}()
+
+	<-ctx.Done()
+	logger.Instance().Info(context.Background(), "Stopping ingestion worker")
+
+	wg.Wait()
+
+	return nil
+}
+
+// poll claims resources until ctx is cancelled. Each ingestion runs under
+// drainCtx, which outlives ctx by the drain timeout, so that a shutdown does
+// not cut it off halfway.
+func (worker ingestionWorker) poll(ctx context.Context, drainCtx context.Context) {
+
+	for ctx.Err() == nil {
+
+		resource, err := worker.ingestUc(drainCtx)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+		}
+
+		// Keep draining the queue while there is work, otherwise back off.
+		if resource != nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(worker.pollInterval):
+		}
+	}
+}
+
+func (worker ingestionWorker) releaseStale(ctx context.Context) {
+
+	ticker := time.NewTicker(worker.pollInterval)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := worker.releaseUc(ctx); err != nil {
+			logger.Instance().Error(ctx, err.Error())
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package runners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+)
+
+type namedRunner struct {
+	name   string
+	runner Runner
+}
+
+type namedCloser struct {
+	name  string
+	close func(context.Context) error
+}
+
+// Lifecycle runs several runners in one process and closes the resources
+// they share once all of them have stopped.
+type Lifecycle struct {
+	closeTimeout time.Duration
+	runners      []namedRunner
+	closers      []namedCloser
+}
+
+func NewLifecycle(closeTimeout time.Duration) *Lifecycle {
+
+	return &Lifecycle{closeTimeout: closeTimeout}
+}
+
+func (lifecycle *Lifecycle) Add(name string, runner Runner) {
+
+	lifecycle.runners = append(lifecycle.runners, namedRunner{name, runner})
+}
+
+// OnClose registers a resource to close on exit. Resources are closed in the
+// reverse order of their registration, so a resource is closed before the
+// ones it was built from.
+func (lifecycle *Lifecycle) OnClose(name string, close func(context.Context) error) {
+
+	lifecycle.closers = append(lifecycle.closers, namedCloser{name, close})
+}
+
+// Run starts every runner and stops all of them as soon as ctx is cancelled
+// or one of them exits, since the process should not keep running with a
+// part of it missing.
+func (lifecycle *Lifecycle) Run(ctx context.Context) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, len(lifecycle.runners))
+
+	var wg sync.WaitGroup
+
+	for i, runner := range lifecycle.runners {
+
+		wg.Add(1)
+
+		go func(i int, runner namedRunner) {
+
+			defer wg.Done()
+			defer cancel()
+
+			if err := runner.runner.Run(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s stopped: %w", runner.name, err)
+			}
+		}(i, runner)
+	}
+
+	wg.Wait()
+
+	return errors.Join(append(errs, lifecycle.Close())...)
+}
+
+// Close closes the registered resources. It is called by Run, and only needs
+// to be called when the runners could not be configured.
+func (lifecycle *Lifecycle) Close() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), lifecycle.closeTimeout)
+	defer cancel()
+
+	var errs []error
+
+	for i := len(lifecycle.closers) - 1; i >= 0; i-- {
+
+		closer := lifecycle.closers[i]
+		logger.Instance().Info(ctx, fmt.Sprintf("Closing %s", closer.name))
+
+		if err := closer.close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("could not close %s: %w", closer.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
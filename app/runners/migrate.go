@@ -0,0 +1,87 @@
+package runners
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/migrations"
+)
+
+const (
+	MigrateUp     = "up"
+	MigrateDown   = "down"
+	MigrateStatus = "status"
+)
+
+type migrateRunner struct {
+	migrator migrations.Migrator
+	command  string
+	steps    int
+}
+
+func NewMigrateRunner(migrator migrations.Migrator, command string, steps int) Runner {
+
+	return &migrateRunner{migrator, command, steps}
+}
+
+func (runner migrateRunner) Run(ctx context.Context) error {
+
+	switch runner.command {
+	case MigrateUp:
+		applied, err := runner.migrator.Up(ctx)
+		runner.logMigrations(ctx, "Applied", applied)
+		return err
+	case MigrateDown:
+		reverted, err := runner.migrator.Down(ctx, runner.steps)
+		runner.logMigrations(ctx, "Reverted", reverted)
+		return err
+	case MigrateStatus:
+		return runner.printStatus(ctx)
+	}
+
+	return fmt.Errorf("unknown migrate command %s", runner.command)
+}
+
+func (runner migrateRunner) logMigrations(ctx context.Context, action string, migrations []migrations.Migration) {
+
+	if len(migrations) < 1 {
+		logger.Instance().Info(ctx, fmt.Sprintf("%s no migrations", action))
+		return
+	}
+
+	for _, migration := range migrations {
+		logger.Instance().Info(ctx, fmt.Sprintf("%s migration %04d_%s", action, migration.Version, migration.Name))
+	}
+}
+
+func (runner migrateRunner) printStatus(ctx context.Context) error {
+
+	statuses, err := runner.migrator.Status(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+
+	for _, status := range statuses {
+
+		appliedAt := "pending"
+
+		if len(status.Requires) > 0 {
+			appliedAt = fmt.Sprintf("pending, needs %s", status.Requires)
+		}
+
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
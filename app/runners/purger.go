@@ -0,0 +1,48 @@
+package runners
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/uc"
+)
+
+type rateLimitPurger struct {
+	interval time.Duration
+	purgeUc  uc.PurgeRateLimitsUc
+}
+
+// NewRateLimitPurger deletes idle rate limit buckets every interval, as every
+// caller and domain that was ever throttled would keep a row otherwise.
+func NewRateLimitPurger(interval time.Duration, purgeUc uc.PurgeRateLimitsUc) Runner {
+
+	return &rateLimitPurger{interval, purgeUc}
+}
+
+func (purger rateLimitPurger) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(purger.interval)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		purged, err := purger.purgeUc(ctx)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			continue
+		}
+
+		if purged > 0 {
+			logger.Instance().Debug(ctx, fmt.Sprintf("Deleted %d idle rate limit buckets", purged))
+		}
+	}
+}
@@ -1,5 +1,9 @@
 package runners
 
+import "context"
+
+// Runner runs until it fails or ctx is cancelled, in which case it stops
+// gracefully and returns nil.
 type Runner interface {
-	Run() error
+	Run(context.Context) error
 }
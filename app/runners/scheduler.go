@@ -0,0 +1,42 @@
+package runners
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/uc"
+)
+
+type refreshScheduler struct {
+	interval  time.Duration
+	requeueUc uc.RequeueDueResourcesUc
+}
+
+func NewRefreshScheduler(interval time.Duration, requeueUc uc.RequeueDueResourcesUc) Runner {
+
+	return &refreshScheduler{interval, requeueUc}
+}
+
+func (scheduler refreshScheduler) Run(ctx context.Context) error {
+
+	logger.Instance().Info(ctx, fmt.Sprintf("Starting refresh scheduler with interval %s", scheduler.interval))
+
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-ctx.Done():
+			logger.Instance().Info(context.Background(), "Stopping refresh scheduler")
+			return nil
+		case <-ticker.C:
+		}
+
+		if _, err := scheduler.requeueUc(ctx); err != nil {
+			logger.Instance().Error(ctx, err.Error())
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+)
+
+// CondenseService rewrites a follow-up question into one that can be understood
+// without the conversation it was asked in.
+type CondenseService interface {
+	Get(context.Context, []entities.Message, string) (string, error)
+}
@@ -0,0 +1,9 @@
+package services
+
+import (
+	"context"
+)
+
+type ContentService interface {
+	Get(context.Context, string) (string, error)
+}
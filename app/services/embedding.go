@@ -0,0 +1,9 @@
+package services
+
+import (
+	"context"
+)
+
+type EmbeddingService interface {
+	Get(context.Context, []string) ([][]float32, error)
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RateLimitedError is returned when an upstream keeps answering 429 after the
+// outbound transport has retried, which includes a used up quota.
+var RateLimitedError = errors.New("rate limited by upstream")
+
+type RateLimitError struct {
+	Upstream string
+	// Quota tells a used up quota, which waiting does not fix, apart from a
+	// rate limit.
+	Quota bool
+}
+
+func (err *RateLimitError) Error() string {
+
+	if err.Quota {
+		return fmt.Sprintf("%s: %s quota exceeded", RateLimitedError, err.Upstream)
+	}
+
+	return fmt.Sprintf("%s: %s", RateLimitedError, err.Upstream)
+}
+
+func (err *RateLimitError) Unwrap() error {
+
+	return RateLimitedError
+}
@@ -0,0 +1,9 @@
+package services
+
+import "context"
+
+// HealthProbe checks whether a dependency can serve requests.
+type HealthProbe interface {
+	Name() string
+	Check(context.Context) error
+}
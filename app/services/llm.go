@@ -0,0 +1,20 @@
+package services
+
+import "context"
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// CompletionRequest asks an LLM to continue a chat. An empty Model falls back
+// to the model the client was configured with.
+type CompletionRequest struct {
+	Model    string
+	Messages []ChatMessage
+}
+
+type LLMClient interface {
+	Complete(context.Context, CompletionRequest) (string, error)
+	Stream(context.Context, CompletionRequest, func(string) error) (string, error)
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// TokenUsage accumulates the tokens spent on paid model calls made on behalf
+// of a request. Clients add to the counter in the context, when there is one.
+type TokenUsage struct {
+	tokens atomic.Int64
+}
+
+type tokenUsageKey struct{}
+
+func WithTokenUsage(ctx context.Context) (context.Context, *TokenUsage) {
+
+	usage := &TokenUsage{}
+	return context.WithValue(ctx, tokenUsageKey{}, usage), usage
+}
+
+func AddTokenUsage(ctx context.Context, tokens int) {
+
+	if usage, ok := ctx.Value(tokenUsageKey{}).(*TokenUsage); ok {
+		usage.tokens.Add(int64(tokens))
+	}
+}
+
+func (usage *TokenUsage) Tokens() int {
+
+	return int(usage.tokens.Load())
+}
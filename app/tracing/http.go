@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler starts a server span for every request, continuing the trace of its
+// traceparent header.
+func Handler(next http.Handler) http.Handler {
+
+	return otelhttp.NewHandler(next, "http", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method
+	}))
+}
+
+// RouteMiddleware names the span of a request after its route once the router
+// has matched it, which Handler cannot know yet.
+func RouteMiddleware(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if current := mux.CurrentRoute(r); current != nil {
+
+			if template, err := current.GetPathTemplate(); err == nil {
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(fmt.Sprintf("%s %s", r.Method, template))
+				span.SetAttributes(semconv.HTTPRoute(template))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewTransport starts a client span for every outgoing request and passes the
+// trace context on in its traceparent header.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+
+	return otelhttp.NewTransport(base, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return fmt.Sprintf("%s %s", r.Method, r.URL.Host)
+	}))
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const batchOperation = "BATCH"
+
+type pgxTracer struct{}
+
+// pgx traces batches when the query tracer is a pgx.BatchTracer as well.
+var _ pgx.BatchTracer = pgxTracer{}
+
+// batchKey holds the *batchClock of the batch being traced.
+type batchKey struct{}
+
+// batchClock remembers when the previous query of a batch finished, which is
+// when the next one started as far as the caller can tell.
+type batchClock struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewPgxTracer starts a span for every query run on a connection, and for
+// every batch with a child span per query. It is set as the Tracer of the
+// connection config of a pool.
+func NewPgxTracer() pgx.QueryTracer {
+
+	return pgxTracer{}
+}
+
+func (pgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+
+	operation := queryOperation(data.SQL)
+
+	ctx, _ = start(ctx, "postgres "+operation,
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(data.SQL))
+
+	return ctx
+}
+
+func (pgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+
+	end(span, data.Err)
+}
+
+func (pgxTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+
+	ctx, _ = start(ctx, "postgres "+batchOperation,
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(batchOperation),
+		attribute.Int("db.operation.batch.size", data.Batch.Len()))
+
+	return context.WithValue(ctx, batchKey{}, &batchClock{last: time.Now()})
+}
+
+func (pgxTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+
+	startedAt := time.Now()
+
+	if clock, ok := ctx.Value(batchKey{}).(*batchClock); ok {
+		clock.mu.Lock()
+		startedAt, clock.last = clock.last, time.Now()
+		clock.mu.Unlock()
+	}
+
+	operation := queryOperation(data.SQL)
+
+	_, span := otel.Tracer(instrumentationName).Start(ctx, "postgres "+operation,
+		trace.WithTimestamp(startedAt),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(data.SQL),
+			attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected())))
+
+	end(span, data.Err)
+}
+
+func (pgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+
+	end(trace.SpanFromContext(ctx), data.Err)
+}
+
+// queryOperation is the first keyword of a statement, e.g. SELECT or INSERT.
+func queryOperation(sql string) string {
+
+	fields := strings.Fields(sql)
+
+	if len(fields) < 1 {
+		return "query"
+	}
+
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type tracedResponseRepo struct {
+	store string
+	next  repos.StreamingResponseRepo
+}
+
+// TraceResponseRepo covers retrieval from the vector store along with the
+// generation of the answer, whose requests are traced on their own.
+func TraceResponseRepo(store string, next repos.StreamingResponseRepo) repos.StreamingResponseRepo {
+
+	return &tracedResponseRepo{store, next}
+}
+
+func (repo *tracedResponseRepo) Get(ctx context.Context, query entities.Query) (response *entities.Response, err error) {
+
+	ctx, span := start(ctx, "repo.GetResponse", attribute.String("kh.vector_store", repo.store), domainIdsAttribute.StringSlice(query.DomainIds))
+	defer func() { end(span, err) }()
+
+	return repo.next.Get(ctx, query)
+}
+
+func (repo *tracedResponseRepo) Stream(ctx context.Context, query entities.Query, handler entities.ResponseEventHandler) (response *entities.Response, err error) {
+
+	ctx, span := start(ctx, "repo.StreamResponse", attribute.String("kh.vector_store", repo.store), domainIdsAttribute.StringSlice(query.DomainIds))
+	defer func() { end(span, err) }()
+
+	return repo.next.Stream(ctx, query, handler)
+}
+
+type tracedConceptService struct {
+	next services.ConceptService
+}
+
+func TraceConceptService(next services.ConceptService) services.ConceptService {
+
+	return &tracedConceptService{next}
+}
+
+func (service *tracedConceptService) Get(ctx context.Context, question string) (concepts []entities.Concept, err error) {
+
+	ctx, span := start(ctx, "service.GetConcepts")
+	defer func() { end(span, err) }()
+
+	return service.next.Get(ctx, question)
+}
+
+type tracedCondenseService struct {
+	next services.CondenseService
+}
+
+func TraceCondenseService(next services.CondenseService) services.CondenseService {
+
+	return &tracedCondenseService{next}
+}
+
+func (service *tracedCondenseService) Get(ctx context.Context, history []entities.Message, question string) (condensed string, err error) {
+
+	ctx, span := start(ctx, "service.CondenseQuestion", attribute.Int("kh.history_length", len(history)))
+	defer func() { end(span, err) }()
+
+	return service.next.Get(ctx, history, question)
+}
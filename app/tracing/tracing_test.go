@@ -0,0 +1,142 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps every ended span in memory.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func attributeOf(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func TestSpansNestFromRequestToQueries(t *testing.T) {
+
+	recorder := recordSpans(t)
+	tracer := pgxTracer{}
+	insertErr := errors.New("duplicate chunk")
+
+	// The use case runs a query and a batch the way the Postgres repos do.
+	getDomainUc := TraceGetDomainUc(func(ctx context.Context, domainId string) (*entities.Domain, error) {
+
+		queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT id FROM domains WHERE id = $1"})
+		tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+
+		batch := &pgx.Batch{}
+		batch.Queue("DELETE FROM chunks WHERE resource_id = $1", 1)
+		batch.Queue("INSERT INTO chunks (resource_id) VALUES ($1)", 1)
+
+		batchCtx := tracer.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{Batch: batch})
+		tracer.TraceBatchQuery(batchCtx, nil, pgx.TraceBatchQueryData{SQL: "DELETE FROM chunks WHERE resource_id = $1", CommandTag: pgconn.NewCommandTag("DELETE 3")})
+		tracer.TraceBatchQuery(batchCtx, nil, pgx.TraceBatchQueryData{SQL: "INSERT INTO chunks (resource_id) VALUES ($1)", Err: insertErr})
+		tracer.TraceBatchEnd(batchCtx, nil, pgx.TraceBatchEndData{Err: insertErr})
+
+		return &entities.Domain{Id: domainId}, nil
+	})
+
+	router := mux.NewRouter()
+	router.Use(RouteMiddleware)
+	router.HandleFunc("/domains/{domain_id}", func(w http.ResponseWriter, r *http.Request) {
+		getDomainUc(r.Context(), mux.Vars(r)["domain_id"])
+	})
+
+	Handler(router).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/domains/Docs", nil))
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	if len(spans) != 6 {
+		t.Fatalf("recorded %d distinct spans, want 6", len(spans))
+	}
+
+	parents := []struct {
+		child  string
+		parent string
+	}{
+		{"uc.GetDomain", "GET /domains/{domain_id}"},
+		{"postgres SELECT", "uc.GetDomain"},
+		{"postgres BATCH", "uc.GetDomain"},
+		{"postgres DELETE", "postgres BATCH"},
+		{"postgres INSERT", "postgres BATCH"},
+	}
+
+	for _, want := range parents {
+
+		child, parent := spans[want.child], spans[want.parent]
+
+		if child == nil || parent == nil {
+			t.Fatalf("spans %q and %q were not both recorded", want.child, want.parent)
+		}
+
+		if child.Parent().SpanID() != parent.SpanContext().SpanID() || child.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("span %q is not a child of %q", want.child, want.parent)
+		}
+	}
+
+	attributes := []struct {
+		span  string
+		key   attribute.Key
+		value attribute.Value
+	}{
+		{"GET /domains/{domain_id}", "http.route", attribute.StringValue("/domains/{domain_id}")},
+		{"uc.GetDomain", domainIdAttribute, attribute.StringValue("Docs")},
+		{"postgres SELECT", "db.system", attribute.StringValue("postgresql")},
+		{"postgres SELECT", "db.query.text", attribute.StringValue("SELECT id FROM domains WHERE id = $1")},
+		{"postgres SELECT", "db.rows_affected", attribute.Int64Value(1)},
+		{"postgres BATCH", "db.operation.batch.size", attribute.IntValue(2)},
+		{"postgres DELETE", "db.rows_affected", attribute.Int64Value(3)},
+		{"postgres INSERT", "db.query.text", attribute.StringValue("INSERT INTO chunks (resource_id) VALUES ($1)")},
+	}
+
+	for _, want := range attributes {
+
+		if got := attributeOf(spans[want.span], want.key); got != want.value {
+			t.Errorf("span %q has %s = %v, want %v", want.span, want.key, got.Emit(), want.value.Emit())
+		}
+	}
+
+	for _, name := range []string{"postgres INSERT", "postgres BATCH"} {
+
+		if status := spans[name].Status(); status.Code != codes.Error || status.Description != insertErr.Error() {
+			t.Errorf("span %q has status %v, want the error", name, status)
+		}
+	}
+
+	if status := spans["postgres DELETE"].Status(); status.Code == codes.Error {
+		t.Errorf("span %q failed, want it to succeed", "postgres DELETE")
+	}
+}
@@ -0,0 +1,301 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/uc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	domainIdAttribute       = attribute.Key("kh.domain_id")
+	domainIdsAttribute      = attribute.Key("kh.domain_ids")
+	resourceIdAttribute     = attribute.Key("kh.resource_id")
+	conversationIdAttribute = attribute.Key("kh.conversation_id")
+)
+
+// traceUc calls a use case in a span, which records the error it returns.
+func traceUc[T any](ctx context.Context, name string, call func(context.Context) (T, error), attributes ...attribute.KeyValue) (result T, err error) {
+
+	ctx, span := start(ctx, name, attributes...)
+	defer func() { end(span, err) }()
+
+	return call(ctx)
+}
+
+// traceErrUc is traceUc for use cases that only return an error.
+func traceErrUc(ctx context.Context, name string, call func(context.Context) error, attributes ...attribute.KeyValue) error {
+
+	_, err := traceUc(ctx, name, func(ctx context.Context) (struct{}, error) { return struct{}{}, call(ctx) }, attributes...)
+
+	return err
+}
+
+func TraceSearchUc(next uc.SearchUc) uc.SearchUc {
+
+	return func(ctx context.Context, query entities.Query) (*entities.Response, error) {
+
+		return traceUc(ctx, "uc.Search", func(ctx context.Context) (*entities.Response, error) {
+			return next(ctx, query)
+		}, domainIdsAttribute.StringSlice(query.DomainIds))
+	}
+}
+
+func TraceStreamSearchUc(next uc.StreamSearchUc) uc.StreamSearchUc {
+
+	return func(ctx context.Context, query entities.Query, handler entities.ResponseEventHandler) (*entities.Response, error) {
+
+		return traceUc(ctx, "uc.StreamSearch", func(ctx context.Context) (*entities.Response, error) {
+			return next(ctx, query, handler)
+		}, domainIdsAttribute.StringSlice(query.DomainIds))
+	}
+}
+
+func TraceDomainStatusValidator(next uc.DomainStatusValidator) uc.DomainStatusValidator {
+
+	return func(ctx context.Context, domainIds []string) ([]string, error) {
+
+		return traceUc(ctx, "uc.ValidateDomainStatus", func(ctx context.Context) ([]string, error) {
+			return next(ctx, domainIds)
+		}, domainIdsAttribute.StringSlice(domainIds))
+	}
+}
+
+func TraceCheckBudgetUc(next uc.CheckBudgetUc) uc.CheckBudgetUc {
+
+	return func(ctx context.Context, domainIds []string) error {
+
+		return traceErrUc(ctx, "uc.CheckBudget", func(ctx context.Context) error {
+			return next(ctx, domainIds)
+		}, domainIdsAttribute.StringSlice(domainIds))
+	}
+}
+
+func TraceRecordUsageUc(next uc.RecordUsageUc) uc.RecordUsageUc {
+
+	return func(ctx context.Context, domainIds []string, tokens int) {
+
+		traceErrUc(ctx, "uc.RecordUsage", func(ctx context.Context) error {
+			next(ctx, domainIds, tokens)
+			return nil
+		}, domainIdsAttribute.StringSlice(domainIds), attribute.Int("kh.tokens", tokens))
+	}
+}
+
+func TraceThrottleUc(next uc.ThrottleUc) uc.ThrottleUc {
+
+	return func(ctx context.Context, client string, domainIds []string) error {
+
+		return traceErrUc(ctx, "uc.Throttle", func(ctx context.Context) error {
+			return next(ctx, client, domainIds)
+		}, domainIdsAttribute.StringSlice(domainIds))
+	}
+}
+
+func TraceAuthenticateUc(next uc.AuthenticateUc) uc.AuthenticateUc {
+
+	return func(ctx context.Context, key string) (*entities.Identity, error) {
+
+		return traceUc(ctx, "uc.Authenticate", func(ctx context.Context) (*entities.Identity, error) {
+			return next(ctx, key)
+		})
+	}
+}
+
+func TraceListDomainsUc(next uc.ListDomainsUc) uc.ListDomainsUc {
+
+	return func(ctx context.Context, filter entities.DomainFilter, page entities.PageRequest) (*entities.Page[entities.Domain], error) {
+
+		return traceUc(ctx, "uc.ListDomains", func(ctx context.Context) (*entities.Page[entities.Domain], error) {
+			return next(ctx, filter, page)
+		})
+	}
+}
+
+func TraceAddDomainUc(next uc.AddDomainUc) uc.AddDomainUc {
+
+	return func(ctx context.Context, domain entities.Domain) (*entities.Domain, error) {
+
+		return traceUc(ctx, "uc.AddDomain", func(ctx context.Context) (*entities.Domain, error) {
+			return next(ctx, domain)
+		}, domainIdAttribute.String(domain.Id))
+	}
+}
+
+func TraceDeleteDomainUc(next uc.DeleteDomainUc) uc.DeleteDomainUc {
+
+	return func(ctx context.Context, domainId string) error {
+
+		return traceErrUc(ctx, "uc.DeleteDomain", func(ctx context.Context) error {
+			return next(ctx, domainId)
+		}, domainIdAttribute.String(domainId))
+	}
+}
+
+func TraceGetDomainUc(next uc.GetDomainUc) uc.GetDomainUc {
+
+	return func(ctx context.Context, domainId string) (*entities.Domain, error) {
+
+		return traceUc(ctx, "uc.GetDomain", func(ctx context.Context) (*entities.Domain, error) {
+			return next(ctx, domainId)
+		}, domainIdAttribute.String(domainId))
+	}
+}
+
+func TraceUpdateDomainUc(next uc.UpdateDomainUc) uc.UpdateDomainUc {
+
+	return func(ctx context.Context, domainId string, patch entities.DomainPatch, version *time.Time) (*entities.Domain, error) {
+
+		return traceUc(ctx, "uc.UpdateDomain", func(ctx context.Context) (*entities.Domain, error) {
+			return next(ctx, domainId, patch, version)
+		}, domainIdAttribute.String(domainId))
+	}
+}
+
+func TraceListResourcesUc(next uc.ListResourcesUc) uc.ListResourcesUc {
+
+	return func(ctx context.Context, filter entities.ResourceFilter, page entities.PageRequest) (*entities.ResourcePage, error) {
+
+		return traceUc(ctx, "uc.ListResources", func(ctx context.Context) (*entities.ResourcePage, error) {
+			return next(ctx, filter, page)
+		}, domainIdAttribute.String(filter.DomainId))
+	}
+}
+
+func TraceAddResourceUc(next uc.AddResourceUc) uc.AddResourceUc {
+
+	return func(ctx context.Context, resource entities.Resource) (*entities.Resource, error) {
+
+		return traceUc(ctx, "uc.AddResource", func(ctx context.Context) (*entities.Resource, error) {
+			return next(ctx, resource)
+		}, domainIdAttribute.String(resource.DomainId))
+	}
+}
+
+func TraceDeleteResourceUc(next uc.DeleteResourceUc) uc.DeleteResourceUc {
+
+	return func(ctx context.Context, domainId string, id int) error {
+
+		return traceErrUc(ctx, "uc.DeleteResource", func(ctx context.Context) error {
+			return next(ctx, domainId, id)
+		}, domainIdAttribute.String(domainId), resourceIdAttribute.Int(id))
+	}
+}
+
+func TraceReingestResourceUc(next uc.ReingestResourceUc) uc.ReingestResourceUc {
+
+	return func(ctx context.Context, domainId string, id int, force bool) (*entities.Resource, error) {
+
+		return traceUc(ctx, "uc.ReingestResource", func(ctx context.Context) (*entities.Resource, error) {
+			return next(ctx, domainId, id, force)
+		}, domainIdAttribute.String(domainId), resourceIdAttribute.Int(id))
+	}
+}
+
+func TraceGetResourceUc(next uc.GetResourceUc) uc.GetResourceUc {
+
+	return func(ctx context.Context, domainId string, id int) (*entities.Resource, error) {
+
+		return traceUc(ctx, "uc.GetResource", func(ctx context.Context) (*entities.Resource, error) {
+			return next(ctx, domainId, id)
+		}, domainIdAttribute.String(domainId), resourceIdAttribute.Int(id))
+	}
+}
+
+func TraceUpdateResourceUc(next uc.UpdateResourceUc) uc.UpdateResourceUc {
+
+	return func(ctx context.Context, domainId string, id int, patch entities.ResourcePatch, version *time.Time) (*entities.Resource, error) {
+
+		return traceUc(ctx, "uc.UpdateResource", func(ctx context.Context) (*entities.Resource, error) {
+			return next(ctx, domainId, id, patch, version)
+		}, domainIdAttribute.String(domainId), resourceIdAttribute.Int(id))
+	}
+}
+
+func TraceStartConversationUc(next uc.StartConversationUc) uc.StartConversationUc {
+
+	return func(ctx context.Context, domainId string) (*entities.Conversation, error) {
+
+		return traceUc(ctx, "uc.StartConversation", func(ctx context.Context) (*entities.Conversation, error) {
+			return next(ctx, domainId)
+		}, domainIdAttribute.String(domainId))
+	}
+}
+
+func TraceGetConversationUc(next uc.GetConversationUc) uc.GetConversationUc {
+
+	return func(ctx context.Context, id int) (*entities.Conversation, error) {
+
+		return traceUc(ctx, "uc.GetConversation", func(ctx context.Context) (*entities.Conversation, error) {
+			return next(ctx, id)
+		}, conversationIdAttribute.Int(id))
+	}
+}
+
+func TraceSendMessageUc(next uc.SendMessageUc) uc.SendMessageUc {
+
+	return func(ctx context.Context, id int, question string) (*entities.Message, error) {
+
+		return traceUc(ctx, "uc.SendMessage", func(ctx context.Context) (*entities.Message, error) {
+			return next(ctx, id, question)
+		}, conversationIdAttribute.Int(id))
+	}
+}
+
+func TraceMintApiKeyUc(next uc.MintApiKeyUc) uc.MintApiKeyUc {
+
+	return func(ctx context.Context, apiKey entities.ApiKey) (*entities.ApiKey, error) {
+
+		return traceUc(ctx, "uc.MintApiKey", func(ctx context.Context) (*entities.ApiKey, error) {
+			return next(ctx, apiKey)
+		})
+	}
+}
+
+func TraceListApiKeysUc(next uc.ListApiKeysUc) uc.ListApiKeysUc {
+
+	return func(ctx context.Context) ([]entities.ApiKey, error) {
+
+		return traceUc(ctx, "uc.ListApiKeys", next)
+	}
+}
+
+func TraceRevokeApiKeyUc(next uc.RevokeApiKeyUc) uc.RevokeApiKeyUc {
+
+	return func(ctx context.Context, id int) error {
+
+		return traceErrUc(ctx, "uc.RevokeApiKey", func(ctx context.Context) error {
+			return next(ctx, id)
+		})
+	}
+}
+
+// TraceIngestNextResourceUc tags its span with the resource that was claimed,
+// which is only known once the use case returns.
+func TraceIngestNextResourceUc(next uc.IngestNextResourceUc) uc.IngestNextResourceUc {
+
+	return func(ctx context.Context) (*entities.Resource, error) {
+
+		return traceUc(ctx, "uc.IngestNextResource", func(ctx context.Context) (*entities.Resource, error) {
+
+			resource, err := next(ctx)
+
+			if resource != nil {
+				trace.SpanFromContext(ctx).SetAttributes(domainIdAttribute.String(resource.DomainId), resourceIdAttribute.Int(resource.Id))
+			}
+
+			return resource, err
+		})
+	}
+}
+
+func TraceRequeueDueResourcesUc(next uc.RequeueDueResourcesUc) uc.RequeueDueResourcesUc {
+
+	return func(ctx context.Context) (int, error) {
+
+		return traceUc(ctx, "uc.RequeueDueResources", next)
+	}
+}
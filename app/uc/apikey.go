@@ -0,0 +1,187 @@
+package uc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+)
+
+const (
+	apiKeyPrefix       = "kh_"
+	apiKeyRandomBytes  = 32
+	apiKeyDisplayChars = 8
+)
+
+type AuthenticateUc func(context.Context, string) (*entities.Identity, error)
+type MintApiKeyUc func(context.Context, entities.ApiKey) (*entities.ApiKey, error)
+type ListApiKeysUc func(context.Context) ([]entities.ApiKey, error)
+type RevokeApiKeyUc func(context.Context, int) error
+
+// NewAuthenticateUc resolves an api key to the identity of its caller. The
+// bootstrap key, when configured, is an admin that exists outside the database
+// so that the first keys can be minted.
+func NewAuthenticateUc(repo repos.ApiKeyRepo, bootstrapKey string) AuthenticateUc {
+
+	bootstrapHash := hashApiKey(bootstrapKey)
+
+	return func(ctx context.Context, key string) (*entities.Identity, error) {
+
+		if len(key) < 1 {
+			return nil, fmt.Errorf("%w: api key not provided", UnauthenticatedError)
+		}
+
+		keyHash := hashApiKey(key)
+
+		if len(bootstrapKey) > 0 && subtle.ConstantTimeCompare([]byte(keyHash), []byte(bootstrapHash)) == 1 {
+			return &entities.Identity{Name: "bootstrap", Role: entities.RoleAdmin}, nil
+		}
+
+		apiKey, err := repo.GetByHash(ctx, keyHash)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not authenticate api key")
+		}
+
+		if apiKey == nil || apiKey.RevokedAt != nil {
+			return nil, fmt.Errorf("%w: invalid api key", UnauthenticatedError)
+		}
+
+		return &entities.Identity{KeyId: apiKey.Id, Name: apiKey.Name, Role: apiKey.Role, DomainIds: apiKey.DomainIds}, nil
+	}
+}
+
+func NewMintApiKeyUc(repo repos.ApiKeyRepo, domainRepo repos.DomainRepo) MintApiKeyUc {
+
+	return func(ctx context.Context, apiKey entities.ApiKey) (*entities.ApiKey, error) {
+
+		if err := validateApiKeyEntity(apiKey); err != nil {
+			logger.Instance().Debug(ctx, err.Error())
+			return nil, err
+		}
+
+		for _, domainId := range apiKey.DomainIds {
+
+			if domainId == entities.AllDomains {
+				continue
+			}
+
+			if ent, _ := domainRepo.Get(ctx, domainId); ent == nil {
+				return nil, invalidField("domainIds", "invalid domain id %s", domainId)
+			}
+		}
+
+		b := make([]byte, apiKeyRandomBytes)
+
+		if _, err := rand.Read(b); err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not generate api key")
+		}
+
+		key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+		if apiKey.DomainIds == nil {
+			apiKey.DomainIds = make([]string, 0)
+		}
+
+		apiKey.Prefix = key[:len(apiKeyPrefix)+apiKeyDisplayChars]
+		apiKey.KeyHash = hashApiKey(key)
+		apiKey.CreatedAt = time.Now()
+		apiKey.RevokedAt = nil
+
+		ent, err := repo.Create(ctx, apiKey)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not create api key")
+		}
+
+		ent.Key = key
+
+		return ent, nil
+	}
+}
+
+func NewListApiKeysUc(repo repos.ApiKeyRepo) ListApiKeysUc {
+
+	return func(ctx context.Context) ([]entities.ApiKey, error) {
+
+		apiKeys, err := repo.List(ctx)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not fetch api key list")
+		}
+
+		return apiKeys, nil
+	}
+}
+
+func NewRevokeApiKeyUc(repo repos.ApiKeyRepo) RevokeApiKeyUc {
+
+	return func(ctx context.Context, id int) error {
+
+		revoked, err := repo.Revoke(ctx, id, time.Now())
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return fmt.Errorf("could not revoke api key")
+		}
+
+		if !revoked {
+			return fmt.Errorf("%w: api key %d does not exist or is already revoked", NotFoundError, id)
+		}
+
+		return nil
+	}
+}
+
+// authorizeRead fails unless the caller of the request may search the domain.
+func authorizeRead(ctx context.Context, domainId string) error {
+
+	if identity, ok := entities.IdentityFromContext(ctx); ok && !identity.CanRead(domainId) {
+		return fmt.Errorf("%w: api key %s cannot access domain %s", ForbiddenError, identity.Name, domainId)
+	}
+
+	return nil
+}
+
+func hashApiKey(key string) string {
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func validateApiKeyEntity(apiKey entities.ApiKey) error {
+
+	var errs []error
+
+	if len(apiKey.Name) < 1 || len(apiKey.Name) > 50 {
+		errs = append(errs, invalidField("name", "the name should be between 1 and 50 characters long."))
+	}
+
+	if !slices.Contains([]string{entities.RoleAdmin, entities.RoleEditor, entities.RoleReader}, apiKey.Role) {
+		errs = append(errs, invalidField("role", "the role should be one of %s, %s, %s.", entities.RoleAdmin, entities.RoleEditor, entities.RoleReader))
+	}
+
+	if apiKey.Role == entities.RoleAdmin && len(apiKey.DomainIds) > 0 {
+		errs = append(errs, invalidField("domainIds", "admin keys can access every domain and cannot be limited to domains."))
+	}
+
+	if apiKey.Role != entities.RoleAdmin && len(apiKey.DomainIds) < 1 {
+		errs = append(errs, invalidField("domainIds", "%s keys need at least one domain id.", apiKey.Role))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,116 @@
+package uc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+)
+
+// maxHistoryMessages caps how many earlier messages are used to condense a follow-up question.
+const maxHistoryMessages = 10
+
+type StartConversationUc func(context.Context, string) (*entities.Conversation, error)
+type GetConversationUc func(context.Context, int) (*entities.Conversation, error)
+type SendMessageUc func(context.Context, int, string) (*entities.Message, error)
+
+func NewStartConversationUc(conversationRepo repos.ConversationRepo, domainRepo repos.DomainRepo) StartConversationUc {
+
+	return func(ctx context.Context, domainId string) (*entities.Conversation, error) {
+
+		if err := authorizeRead(ctx, domainId); err != nil {
+			return nil, err
+		}
+
+		if ent, _ := domainRepo.Get(ctx, domainId); ent == nil {
+			return nil, fmt.Errorf("%w: domain %s does not exist", NotFoundError, domainId)
+		}
+
+		ent, err := conversationRepo.Create(ctx, entities.Conversation{DomainId: domainId, CreatedAt: time.Now()})
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not create conversation")
+		}
+
+		return ent, nil
+	}
+}
+
+func NewGetConversationUc(conversationRepo repos.ConversationRepo) GetConversationUc {
+
+	return func(ctx context.Context, id int) (*entities.Conversation, error) {
+
+		ent, err := conversationRepo.Get(ctx, id)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not fetch conversation")
+		}
+
+		if ent == nil {
+			return nil, fmt.Errorf("%w: conversation %d does not exist", NotFoundError, id)
+		}
+
+		if err = authorizeRead(ctx, ent.DomainId); err != nil {
+			return nil, err
+		}
+
+		return ent, nil
+	}
+}
+
+func NewSendMessageUc(conversationRepo repos.ConversationRepo, searchUc SearchUc) SendMessageUc {
+
+	return func(ctx context.Context, id int, question string) (*entities.Message, error) {
+
+		if question = strings.TrimSpace(question); len(question) < 1 {
+			return nil, invalidField("question", "question cannot be empty")
+		}
+
+		conversation, err := conversationRepo.Get(ctx, id)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not fetch conversation")
+		}
+
+		if conversation == nil {
+			return nil, fmt.Errorf("%w: conversation %d does not exist", NotFoundError, id)
+		}
+
+		if err = authorizeRead(ctx, conversation.DomainId); err != nil {
+			return nil, err
+		}
+
+		history := conversation.Messages
+
+		if len(history) > maxHistoryMessages {
+			history = history[len(history)-maxHistoryMessages:]
+		}
+
+		askedAt := time.Now()
+
+		answer, err := searchUc(ctx, entities.Query{Question: question, DomainIds: []string{conversation.DomainId}, History: history})
+
+		if err != nil {
+			return nil, err
+		}
+
+		messages, err := conversationRepo.AddMessages(ctx, id, []entities.Message{
+			{Role: entities.MessageRoleUser, Content: question, CreatedAt: askedAt},
+			{Role: entities.MessageRoleAssistant, Content: answer.Response, Sources: answer.Sources, CreatedAt: time.Now()},
+		})
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not save conversation messages")
+		}
+
+		return &messages[1], nil
+	}
+}
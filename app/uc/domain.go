@@ -2,8 +2,10 @@ package uc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"time"
 
 	"github.com/utsavgupta/knowledge-hub/app/entities"
@@ -15,26 +17,45 @@ var (
 	domainIdRegEx = regexp.MustCompile("^[A-Z][a-z]{,4}([a-z]+?_*?[a-z]+?){,10}?")
 )
 
-type ListDomainsUc func(context.Context) ([]entities.Domain, error)
+type ListDomainsUc func(context.Context, entities.DomainFilter, entities.PageRequest) (*entities.Page[entities.Domain], error)
 type AddDomainUc func(context.Context, entities.Domain) (*entities.Domain, error)
 type DeleteDomainUc func(context.Context, string) error
+type GetDomainUc func(context.Context, string) (*entities.Domain, error)
 
+// UpdateDomainUc applies a patch to a domain. When a version is given, the
+// domain is only changed if it is still at that version.
+type UpdateDomainUc func(context.Context, string, entities.DomainPatch, *time.Time) (*entities.Domain, error)
+
+// NewListDomainsUc lists a page of the domains the caller can read.
 func NewListDomainsUc(repo repos.DomainRepo) ListDomainsUc {
 
-	return func(ctx context.Context) ([]entities.Domain, error) {
+	return func(ctx context.Context, filter entities.DomainFilter, page entities.PageRequest) (*entities.Page[entities.Domain], error) {
+
+		if err := normalizePageRequest(&page, domainSortFields, false); err != nil {
+			logger.Instance().Debug(ctx, err.Error())
+			return nil, err
+		}
 
-		entities, err := repo.List(ctx)
+		if len(filter.Search) > 100 {
+			return nil, invalidField("q", "the search text can be 100 characters long")
+		}
+
+		if identity, ok := entities.IdentityFromContext(ctx); ok && !identity.IsAdmin() && !slices.Contains(identity.DomainIds, entities.AllDomains) {
+			filter.Ids = identity.DomainIds
+		}
+
+		domains, err := repo.Find(ctx, filter, page)
 
 		if err != nil {
 			logger.Instance().Error(ctx, err.Error())
 			return nil, fmt.Errorf("could not fetch domain list")
 		}
 
-		return entities, nil
+		return domains, nil
 	}
 }
 
-func NewAddDomainUc(repo repos.DomainRepo) AddDomainUc {
+func NewAddDomainUc(repo repos.DomainRepo, chunkRepo repos.ChunkRepo) AddDomainUc {
 
 	return func(ctx context.Context, domain entities.Domain) (*entities.Domain, error) {
 
@@ -44,7 +65,7 @@ func NewAddDomainUc(repo repos.DomainRepo) AddDomainUc {
 		}
 
 		if ent, _ := repo.Get(ctx, domain.Id); ent != nil {
-			return nil, fmt.Errorf("%w: domain id already exists", ValidationError)
+			return nil, fmt.Errorf("%w: domain id %s already exists", ConflictError, domain.Id)
 		}
 
 		domain.CreatedAt = time.Now()
@@ -56,18 +77,46 @@ func NewAddDomainUc(repo repos.DomainRepo) AddDomainUc {
 			return nil, fmt.Errorf("could not create domain")
 		}
 
+		if err = chunkRepo.CreateCollection(ctx, domain.Id); err != nil {
+			logger.Instance().Error(ctx, err.Error())
+
+			// Undo the insert so the domain can be added again once the vector store is reachable.
+			if err = repo.Delete(ctx, domain.Id); err != nil {
+				logger.Instance().Error(ctx, err.Error())
+			}
+
+			return nil, fmt.Errorf("could not create domain")
+		}
+
 		return ent, nil
 	}
 }
 
-func NewDeleteDomainUc(repo repos.DomainRepo) DeleteDomainUc {
+func NewDeleteDomainUc(repo repos.DomainRepo, resourceRepo repos.ResourceRepo, chunkRepo repos.ChunkRepo) DeleteDomainUc {
 
 	return func(ctx context.Context, id string) error {
 
-		err := repo.Delete(ctx, id)
+		domain, err := repo.Get(ctx, id)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return fmt.Errorf("could not fetch domain")
+		}
+
+		if domain == nil {
+			return fmt.Errorf("%w: domain %s does not exist", NotFoundError, id)
+		}
+
+		if err := chunkRepo.DeleteCollection(ctx, id); err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return fmt.Errorf("could not delete domain")
+		}
+
+		err = repo.Delete(ctx, id)
 
 		if err != nil {
 			logger.Instance().Error(ctx, err.Error())
+			restoreDomainCollection(ctx, id, resourceRepo, chunkRepo)
 			return fmt.Errorf("could not delete domain")
 		}
 
@@ -75,19 +124,135 @@ func NewDeleteDomainUc(repo repos.DomainRepo) DeleteDomainUc {
 	}
 }
 
+func NewGetDomainUc(repo repos.DomainRepo) GetDomainUc {
+
+	return func(ctx context.Context, id string) (*entities.Domain, error) {
+
+		domain, err := repo.Get(ctx, id)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not fetch domain")
+		}
+
+		if domain == nil {
+			return nil, fmt.Errorf("%w: domain %s does not exist", NotFoundError, id)
+		}
+
+		return domain, nil
+	}
+}
+
+func NewUpdateDomainUc(repo repos.DomainRepo) UpdateDomainUc {
+
+	return func(ctx context.Context, id string, patch entities.DomainPatch, version *time.Time) (*entities.Domain, error) {
+
+		domain, err := repo.Get(ctx, id)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not fetch domain")
+		}
+
+		if domain == nil {
+			return nil, fmt.Errorf("%w: domain %s does not exist", NotFoundError, id)
+		}
+
+		current := domain.Version()
+
+		if version != nil && !version.Equal(current) {
+			return nil, fmt.Errorf("%w: domain %s has changed since it was read", PreconditionFailedError, id)
+		}
+
+		patch.Apply(domain)
+
+		if err = validateDomainEntity(*domain); err != nil {
+			logger.Instance().Debug(ctx, err.Error())
+			return nil, err
+		}
+
+		now := time.Now()
+		domain.UpdatedAt = &now
+
+		updated, err := repo.Update(ctx, *domain, current)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not update domain")
+		}
+
+		if updated == nil {
+			return nil, fmt.Errorf("%w: domain %s has changed since it was read", PreconditionFailedError, id)
+		}
+
+		return updated, nil
+	}
+}
+
+// restoreDomainCollection recreates the vector store collection of a domain
+// that could not be deleted and queues its resources for ingestion again, as
+// their chunks are already gone.
+func restoreDomainCollection(ctx context.Context, id string, resourceRepo repos.ResourceRepo, chunkRepo repos.ChunkRepo) {
+
+	if err := chunkRepo.CreateCollection(ctx, id); err != nil {
+		logger.Instance().Error(ctx, err.Error())
+		return
+	}
+
+	resources, err := resourceRepo.List(ctx, id)
+
+	if err != nil {
+		logger.Instance().Error(ctx, err.Error())
+		return
+	}
+
+	now := time.Now()
+
+	for _, resource := range resources {
+
+		resource.Status = entities.ResourceStatusNew
+		resource.IngestionStartedAt = nil
+		resource.IngestionCompletedAt = nil
+		resource.LastError = nil
+		resource.NextAttemptAt = nil
+		// The chunks are gone, so the content has to be embedded again even
+		// if it has not changed.
+		resource.ContentHash = nil
+		resource.UpdatedAt = &now
+
+		if _, err := resourceRepo.UpdateIngestionStatus(ctx, resource); err != nil {
+			logger.Instance().Error(ctx, err.Error())
+		}
+	}
+}
+
 func validateDomainEntity(domain entities.Domain) error {
 
+	var errs []error
+
 	if !domainIdRegEx.MatchString(domain.Id) || len(domain.Id) > 15 {
-		return fmt.Errorf("%w: the id can be at most 15 characters long. it should start will an upper case character and may contain an underscore.", ValidationError)
+		errs = append(errs, invalidField("id", "the id can be at most 15 characters long. it should start will an upper case character and may contain an underscore."))
 	}
 
 	if len(domain.Name) > 50 {
-		return fmt.Errorf("%w: the name can be 50 characters long.", ValidationError)
+		errs = append(errs, invalidField("name", "the name can be 50 characters long."))
+	}
+
+	if len(domain.Description) > 140 {
+		errs = append(errs, invalidField("description", "the description can be 140 characters long."))
+	}
+
+	if domain.Model != nil && (len(*domain.Model) < 1 || len(*domain.Model) > 100) {
+		errs = append(errs, invalidField("model", "the model should be between 1 and 100 characters long."))
+	}
+
+	if domain.DailyTokenBudget != nil && *domain.DailyTokenBudget < 0 {
+		errs = append(errs, invalidField("dailyTokenBudget", "token budgets cannot be negative."))
 	}
 
-	if len(domain.Name) > 140 {
-		return fmt.Errorf("%w: the description can be 140 characters long.", ValidationError)
+	if domain.MonthlyTokenBudget != nil && *domain.MonthlyTokenBudget < 0 {
+		errs = append(errs, invalidField("monthlyTokenBudget", "token budgets cannot be negative."))
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
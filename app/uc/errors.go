@@ -1,7 +1,82 @@
 package uc
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
 
 var (
-	ValidationError = errors.New("invalid input")
+	ValidationError         = errors.New("invalid input")
+	UnauthenticatedError    = errors.New("unauthenticated")
+	ForbiddenError          = errors.New("forbidden")
+	LimitExceededError      = errors.New("limit exceeded")
+	NotFoundError           = errors.New("not found")
+	PreconditionFailedError = errors.New("precondition failed")
+	ConflictError           = errors.New("conflict")
+	UnavailableError        = errors.New("unavailable")
+	// UpstreamRateLimitedError tells an upstream that refuses calls because of
+	// its rate limit or quota apart from one that cannot be reached.
+	UpstreamRateLimitedError = errors.New("upstream rate limited")
 )
+
+// FieldError reports a field of the input that is invalid. Several of them
+// are reported at once with errors.Join.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func invalidField(field string, format string, args ...any) *FieldError {
+
+	return &FieldError{field, fmt.Sprintf(format, args...)}
+}
+
+func (err *FieldError) Error() string {
+
+	return fmt.Sprintf("%s: %s", ValidationError, err.Reason)
+}
+
+func (err *FieldError) Unwrap() error {
+
+	return ValidationError
+}
+
+// upstreamError classifies the failure of an upstream call made to answer a
+// question.
+func upstreamError(err error, message string) error {
+
+	var limitErr *services.RateLimitError
+
+	if errors.Is(err, repos.NoCitationsError) {
+		return fmt.Errorf("%w: nothing relevant to the question was found", NotFoundError)
+	}
+
+	if errors.As(err, &limitErr) {
+		return fmt.Errorf("%w: %s: %s", UpstreamRateLimitedError, message, limitErr.Error())
+	}
+
+	return fmt.Errorf("%w: %s", UnavailableError, message)
+}
+
+// LimitError reports an exceeded rate limit or budget along with how long the
+// caller should wait before trying again.
+type LimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+	// Budget tells an exhausted token budget apart from a rate limit.
+	Budget bool
+}
+
+func (err *LimitError) Error() string {
+
+	return fmt.Sprintf("%s: %s", LimitExceededError, err.Reason)
+}
+
+func (err *LimitError) Unwrap() error {
+
+	return LimitExceededError
+}
@@ -0,0 +1,91 @@
+package uc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
+
+type CheckReadinessUc func(context.Context) entities.Readiness
+
+// NewCheckReadinessUc probes every dependency concurrently, each within
+// timeout. The app is ready when all of them are healthy. breakerStates, when
+// set, adds the state of the circuit breakers of upstream hosts to the
+// report; an open breaker does not make the app unready on its own.
+func NewCheckReadinessUc(probes []services.HealthProbe, timeout time.Duration, breakerStates func() map[string]string) CheckReadinessUc {
+
+	var mu sync.Mutex
+	lastErrors := make(map[string]entities.DependencyHealth)
+
+	return func(ctx context.Context) entities.Readiness {
+
+		readiness := entities.Readiness{Ready: true, Dependencies: make([]entities.DependencyHealth, len(probes))}
+
+		var wg sync.WaitGroup
+
+		for i, probe := range probes {
+
+			wg.Add(1)
+
+			go func(i int, probe services.HealthProbe) {
+				defer wg.Done()
+				readiness.Dependencies[i] = checkDependency(ctx, probe, timeout)
+			}(i, probe)
+		}
+
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for i, dependency := range readiness.Dependencies {
+
+			if dependency.Healthy {
+
+				if last, ok := lastErrors[dependency.Name]; ok {
+					readiness.Dependencies[i].LastError, readiness.Dependencies[i].LastErrorAt = last.LastError, last.LastErrorAt
+				}
+
+				continue
+			}
+
+			readiness.Ready = false
+			lastErrors[dependency.Name] = dependency
+			logger.Instance().Warn(ctx, fmt.Sprintf("dependency %s is not ready: %s", dependency.Name, *dependency.LastError))
+		}
+
+		if breakerStates != nil {
+			readiness.Breakers = breakerStates()
+		}
+
+		return readiness
+	}
+}
+
+func checkDependency(ctx context.Context, probe services.HealthProbe, timeout time.Duration) entities.DependencyHealth {
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := probe.Check(ctx)
+
+	dependency := entities.DependencyHealth{
+		Name:      probe.Name(),
+		Healthy:   err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CheckedAt: start,
+	}
+
+	if err != nil {
+		message := err.Error()
+		dependency.LastError, dependency.LastErrorAt = &message, &start
+	}
+
+	return dependency
+}
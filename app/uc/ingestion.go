@@ -0,0 +1,314 @@
+package uc
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+	"github.com/utsavgupta/knowledge-hub/app/services"
+)
+
+const (
+	chunkSize = 500
+	// statusWriteTimeout bounds the writes that record the outcome of an
+	// ingestion, which are made even if the ingestion was interrupted.
+	statusWriteTimeout = 5 * time.Second
+)
+
+var (
+	chunkSeparators = []string{"\n\n", "\n", " "}
+)
+
+// IngestNextResourceUc claims the next NEW resource, or a FAILED one that is
+// due for a retry, and indexes it. It returns nil when there is nothing left
+// to ingest.
+type IngestNextResourceUc func(context.Context) (*entities.Resource, error)
+
+// ReleaseStaleResourcesUc returns resources that have been INGESTING for
+// longer than the lease timeout to the queue and reports how many it released.
+type ReleaseStaleResourcesUc func(context.Context) (int, error)
+
+// RequeueDueResourcesUc queues ingested resources whose refresh interval has
+// elapsed and reports how many it queued.
+type RequeueDueResourcesUc func(context.Context) (int, error)
+
+// RetryPolicy decides when a failed ingestion is attempted again. The delay
+// doubles after every attempt, starting at BaseDelay and capped at MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// NextAttemptAt returns nil once the resource has used up all its attempts.
+func (policy RetryPolicy) NextAttemptAt(attempts int, now time.Time) *time.Time {
+
+	if attempts >= policy.MaxAttempts {
+		return nil
+	}
+
+	delay := policy.BaseDelay
+
+	for i := 1; i < attempts && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	next := now.Add(min(delay, policy.MaxDelay))
+
+	return &next
+}
+
+func NewIngestNextResourceUc(resourceRepo repos.ResourceRepo, chunkRepo repos.ChunkRepo, contentService services.ContentService, embeddingService services.EmbeddingService, retryPolicy RetryPolicy) IngestNextResourceUc {
+
+	return func(ctx context.Context) (*entities.Resource, error) {
+
+		resource, err := resourceRepo.ClaimNew(ctx, time.Now())
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not claim resource for ingestion")
+		}
+
+		if resource == nil {
+			return nil, nil
+		}
+
+		ctx = logger.WithFields(ctx, logger.Fields{logger.FieldDomainId: resource.DomainId, logger.FieldResourceId: resource.Id})
+
+		logger.Instance().Info(ctx, fmt.Sprintf("Starting to ingest resource %s into domain %s", resource.Url, resource.DomainId))
+
+		contentHash, err := ingestResource(ctx, *resource, chunkRepo, contentService, embeddingService)
+
+		if err != nil && ctx.Err() != nil {
+			releaseInterruptedResource(ctx, resourceRepo, *resource)
+			return nil, fmt.Errorf("ingestion of resource %d was interrupted", resource.Id)
+		}
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			markResourceAsFailed(ctx, resourceRepo, *resource, err, retryPolicy)
+			return nil, fmt.Errorf("could not ingest resource %d", resource.Id)
+		}
+
+		now := time.Now()
+		resource.ContentHash = &contentHash
+		resource.Status = entities.ResourceStatusIngested
+		resource.IngestionCompletedAt = &now
+		resource.UpdatedAt = &now
+		resource.LastError = nil
+		resource.NextAttemptAt = nil
+
+		writeCtx, cancel := statusWriteContext(ctx)
+		defer cancel()
+
+		if resource, err = resourceRepo.UpdateIngestionStatus(writeCtx, *resource); err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not mark resource %d as ingested", resource.Id)
+		}
+
+		logger.Instance().Info(ctx, fmt.Sprintf("Completed ingesting resource %s into domain %s", resource.Url, resource.DomainId))
+
+		return resource, nil
+	}
+}
+
+func NewReleaseStaleResourcesUc(resourceRepo repos.ResourceRepo, leaseTimeout time.Duration, retryPolicy RetryPolicy) ReleaseStaleResourcesUc {
+
+	return func(ctx context.Context) (int, error) {
+
+		now := time.Now()
+		released, err := resourceRepo.ReleaseStale(ctx, now.Add(-leaseTimeout), now, retryPolicy.MaxAttempts)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return 0, fmt.Errorf("could not release stale resources")
+		}
+
+		if released > 0 {
+			logger.Instance().Warn(ctx, fmt.Sprintf("Released %d resources whose ingestion lease expired", released))
+		}
+
+		return released, nil
+	}
+}
+
+func NewRequeueDueResourcesUc(resourceRepo repos.ResourceRepo) RequeueDueResourcesUc {
+
+	return func(ctx context.Context) (int, error) {
+
+		requeued, err := resourceRepo.RequeueDue(ctx, time.Now())
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return 0, fmt.Errorf("could not requeue resources due for a refresh")
+		}
+
+		if requeued > 0 {
+			logger.Instance().Info(ctx, fmt.Sprintf("Queued %d resources for a refresh", requeued))
+		}
+
+		return requeued, nil
+	}
+}
+
+func markResourceAsFailed(ctx context.Context, resourceRepo repos.ResourceRepo, resource entities.Resource, cause error, retryPolicy RetryPolicy) {
+
+	now := time.Now()
+	lastError := cause.Error()
+
+	resource.Status = entities.ResourceStatusFailed
+	resource.LastError = &lastError
+	resource.NextAttemptAt = retryPolicy.NextAttemptAt(resource.Attempts, now)
+	resource.UpdatedAt = &now
+
+	writeCtx, cancel := statusWriteContext(ctx)
+	defer cancel()
+
+	if _, err := resourceRepo.UpdateIngestionStatus(writeCtx, resource); err != nil {
+		logger.Instance().Error(ctx, err.Error())
+	}
+}
+
+// releaseInterruptedResource returns a resource whose ingestion was cut off
+// by a shutdown to the queue, without counting the attempt.
+func releaseInterruptedResource(ctx context.Context, resourceRepo repos.ResourceRepo, resource entities.Resource) {
+
+	now := time.Now()
+
+	logger.Instance().Warn(ctx, fmt.Sprintf("Returning resource %s to the queue as its ingestion was interrupted", resource.Url))
+
+	resource.Status = entities.ResourceStatusNew
+	resource.Attempts = max(resource.Attempts-1, 0)
+	resource.NextAttemptAt = nil
+	resource.UpdatedAt = &now
+
+	writeCtx, cancel := statusWriteContext(ctx)
+	defer cancel()
+
+	if _, err := resourceRepo.UpdateIngestionStatus(writeCtx, resource); err != nil {
+		logger.Instance().Error(ctx, err.Error())
+	}
+}
+
+// statusWriteContext keeps the values of ctx but not its cancellation, so that
+// the outcome of an ingestion is recorded even when ctx is already done.
+func statusWriteContext(ctx context.Context) (context.Context, context.CancelFunc) {
+
+	return context.WithTimeout(context.WithoutCancel(ctx), statusWriteTimeout)
+}
+
+// ingestResource indexes the current content of a resource and returns its
+// hash. Content whose hash matches the previous ingestion is not embedded again.
+func ingestResource(ctx context.Context, resource entities.Resource, chunkRepo repos.ChunkRepo, contentService services.ContentService, embeddingService services.EmbeddingService) (string, error) {
+
+	content, err := contentService.Get(ctx, resource.Url)
+
+	if err != nil {
+		return "", err
+	}
+
+	contentHash := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+
+	if resource.ContentHash != nil && *resource.ContentHash == contentHash {
+		logger.Instance().Info(ctx, fmt.Sprintf("Content of resource %s has not changed since the last ingestion", resource.Url))
+		return contentHash, nil
+	}
+
+	texts := splitText(content, chunkSize, chunkSeparators)
+
+	if len(texts) < 1 {
+
+		// The chunks of the previous content must not be served any more.
+		if err := chunkRepo.Replace(ctx, resource.DomainId, resource.Id, nil); err != nil {
+			return "", err
+		}
+
+		return "", fmt.Errorf("no text could be extracted from %s", resource.Url)
+	}
+
+	vectors, err := embeddingService.Get(ctx, texts)
+
+	if err != nil {
+		return "", err
+	}
+
+	chunks := make([]entities.Chunk, 0, len(texts))
+
+	for i, text := range texts {
+		chunks = append(chunks, entities.Chunk{DomainId: resource.DomainId, ResourceId: resource.Id, ContentHash: contentHash, Index: i, Text: text, Source: resource.Url, Vector: vectors[i]})
+	}
+
+	return contentHash, chunkRepo.Replace(ctx, resource.DomainId, resource.Id, chunks)
+}
+
+// splitText breaks text into chunks of at most size characters, preferring to
+// split on paragraphs, then lines, then words before cutting words apart.
+func splitText(text string, size int, separators []string) []string {
+
+	if utf8.RuneCountInString(text) <= size {
+
+		if text = strings.TrimSpace(text); len(text) < 1 {
+			return nil
+		}
+
+		return []string{text}
+	}
+
+	if len(separators) < 1 {
+
+		runes := []rune(text)
+		chunks := make([]string, 0, len(runes)/size+1)
+
+		for start := 0; start < len(runes); start += size {
+			chunks = append(chunks, string(runes[start:min(start+size, len(runes))]))
+		}
+
+		return chunks
+	}
+
+	separator := separators[0]
+	chunks := make([]string, 0)
+	current := ""
+
+	flush := func() {
+
+		if current = strings.TrimSpace(current); len(current) > 0 {
+			chunks = append(chunks, current)
+		}
+
+		current = ""
+	}
+
+	for _, piece := range strings.Split(text, separator) {
+
+		if utf8.RuneCountInString(piece) > size {
+			flush()
+			chunks = append(chunks, splitText(piece, size, separators[1:])...)
+			continue
+		}
+
+		candidate := piece
+
+		if len(current) > 0 {
+			candidate = current + separator + piece
+		}
+
+		if utf8.RuneCountInString(candidate) > size {
+			flush()
+			current = piece
+			continue
+		}
+
+		current = candidate
+	}
+
+	flush()
+
+	return chunks
+}
@@ -0,0 +1,210 @@
+package uc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+	"github.com/utsavgupta/knowledge-hub/app/logger"
+	"github.com/utsavgupta/knowledge-hub/app/repos"
+)
+
+// RateLimit allows PerMinute requests per minute on average and bursts of up
+// to Burst requests. A PerMinute of 0 disables the limit.
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// BudgetLimits are the default token budgets of domains that do not set their
+// own. A budget of 0 is unlimited.
+type BudgetLimits struct {
+	DailyTokens   int
+	MonthlyTokens int
+}
+
+type ThrottleUc func(context.Context, string, []string) error
+type PurgeRateLimitsUc func(context.Context) (int, error)
+type CheckBudgetUc func(context.Context, []string) error
+type RecordUsageUc func(context.Context, []string, int)
+
+// NewThrottleUc takes a token from the bucket of the client, i.e. its api key
+// or address, and from the bucket of every domain the request is about. Only
+// domains that exist and that the caller may read are charged, so that
+// nobody can drain the bucket of a domain they cannot use, nor create buckets
+// for made-up ids. The request is let through when the buckets cannot be
+// reached.
+func NewThrottleUc(repo repos.RateLimitRepo, domainRepo repos.DomainRepo, clientLimit RateLimit, domainLimit RateLimit) ThrottleUc {
+
+	return func(ctx context.Context, client string, domainIds []string) error {
+
+		now := time.Now()
+
+		if err := takeToken(ctx, repo, "client:"+client, clientLimit, now); err != nil {
+			return err
+		}
+
+		if domainLimit.PerMinute < 1 {
+			return nil
+		}
+
+		identity, authenticated := entities.IdentityFromContext(ctx)
+
+		for _, domainId := range domainIds {
+
+			if authenticated && !identity.CanRead(domainId) {
+				continue
+			}
+
+			domain, err := domainRepo.Get(ctx, domainId)
+
+			if err != nil {
+				logger.Instance().Error(ctx, fmt.Sprintf("could not fetch domain %s to rate limit it: %s", domainId, err.Error()))
+				continue
+			}
+
+			if domain == nil {
+				continue
+			}
+
+			if err := takeToken(ctx, repo, "domain:"+domain.Id, domainLimit, now); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// NewPurgeRateLimitsUc deletes the buckets that have not been used for long
+// enough to be full again, which is no different from having no bucket.
+func NewPurgeRateLimitsUc(repo repos.RateLimitRepo, clientLimit RateLimit, domainLimit RateLimit) PurgeRateLimitsUc {
+
+	idleAfter := max(clientLimit.refillTime(), domainLimit.refillTime(), time.Minute)
+
+	return func(ctx context.Context) (int, error) {
+
+		return repo.DeleteIdle(ctx, time.Now().Add(-idleAfter))
+	}
+}
+
+// refillTime is how long an empty bucket takes to fill up.
+func (limit RateLimit) refillTime() time.Duration {
+
+	if limit.PerMinute < 1 {
+		return 0
+	}
+
+	return time.Duration(max(limit.Burst, 1)) * time.Minute / time.Duration(limit.PerMinute)
+}
+
+func NewCheckBudgetUc(repo repos.BudgetRepo, domainRepo repos.DomainRepo, defaults BudgetLimits) CheckBudgetUc {
+
+	return func(ctx context.Context, domainIds []string) error {
+
+		now := time.Now()
+
+		for _, domainId := range domainIds {
+
+			domain, err := domainRepo.Get(ctx, domainId)
+
+			if err != nil || domain == nil {
+				logger.Instance().Error(ctx, fmt.Sprintf("could not fetch budget of domain %s: %v", domainId, err))
+				continue
+			}
+
+			dailyBudget, monthlyBudget := domainBudget(*domain, defaults)
+
+			if dailyBudget < 1 && monthlyBudget < 1 {
+				continue
+			}
+
+			daily, monthly, err := repo.Usage(ctx, domainId, now)
+
+			if err != nil {
+				logger.Instance().Error(ctx, err.Error())
+				continue
+			}
+
+			nextDay, nextMonth := nextUsagePeriods(now)
+
+			if monthlyBudget > 0 && monthly >= monthlyBudget {
+				return &LimitError{fmt.Sprintf("domain %s has used its monthly budget of %d tokens", domainId, monthlyBudget), nextMonth.Sub(now), true}
+			}
+
+			if dailyBudget > 0 && daily >= dailyBudget {
+				return &LimitError{fmt.Sprintf("domain %s has used its daily budget of %d tokens", domainId, dailyBudget), nextDay.Sub(now), true}
+			}
+		}
+
+		return nil
+	}
+}
+
+// NewRecordUsageUc charges the tokens of a search to the domains it covered,
+// split evenly between them.
+func NewRecordUsageUc(repo repos.BudgetRepo) RecordUsageUc {
+
+	return func(ctx context.Context, domainIds []string, tokens int) {
+
+		if tokens < 1 || len(domainIds) < 1 {
+			return
+		}
+
+		share := (tokens + len(domainIds) - 1) / len(domainIds)
+		now := time.Now()
+
+		for _, domainId := range domainIds {
+
+			// The request may have been cancelled, but the tokens were spent regardless.
+			if err := repo.Record(context.WithoutCancel(ctx), domainId, share, now); err != nil {
+				logger.Instance().Error(ctx, err.Error())
+			}
+		}
+	}
+}
+
+func takeToken(ctx context.Context, repo repos.RateLimitRepo, key string, limit RateLimit, now time.Time) error {
+
+	if limit.PerMinute < 1 {
+		return nil
+	}
+
+	wait, err := repo.Take(ctx, key, float64(max(limit.Burst, 1)), float64(limit.PerMinute)/60, now)
+
+	if err != nil {
+		logger.Instance().Error(ctx, err.Error())
+		return nil
+	}
+
+	if wait > 0 {
+		return &LimitError{fmt.Sprintf("rate limit of %d requests per minute reached for %s", limit.PerMinute, key), wait, false}
+	}
+
+	return nil
+}
+
+func domainBudget(domain entities.Domain, defaults BudgetLimits) (int, int) {
+
+	daily, monthly := defaults.DailyTokens, defaults.MonthlyTokens
+
+	if domain.DailyTokenBudget != nil {
+		daily = *domain.DailyTokenBudget
+	}
+
+	if domain.MonthlyTokenBudget != nil {
+		monthly = *domain.MonthlyTokenBudget
+	}
+
+	return daily, monthly
+}
+
+// nextUsagePeriods returns the start of the UTC day and month after now, when
+// the daily and monthly budgets are replenished.
+func nextUsagePeriods(now time.Time) (time.Time, time.Time) {
+
+	now = now.UTC()
+
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC), time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,105 @@
+package uc
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/utsavgupta/knowledge-hub/app/entities"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+var (
+	domainSortFields   = []string{entities.SortById, entities.SortByName, entities.SortByCreatedAt}
+	resourceSortFields = []string{entities.SortById, entities.SortByName, entities.SortByUrl, entities.SortByStatus, entities.SortByCreatedAt}
+	resourceStatuses   = []string{entities.ResourceStatusNew, entities.ResourceStatusIngesting, entities.ResourceStatusIngested, entities.ResourceStatusFailed}
+)
+
+// normalizePageRequest fills in the defaults of a page request. A cursor
+// keeps the order it was made in. numericIds tells whether the ids of the
+// entities, and so those in cursors, are integers.
+func normalizePageRequest(page *entities.PageRequest, sortFields []string, numericIds bool) error {
+
+	if page.Limit == 0 {
+		page.Limit = defaultPageLimit
+	}
+
+	if page.Limit < 1 || page.Limit > maxPageLimit {
+		return invalidField("limit", "the limit should be between 1 and %d", maxPageLimit)
+	}
+
+	if page.Cursor != nil {
+
+		if len(page.SortBy) > 0 && (page.SortBy != page.Cursor.SortBy || page.Descending != page.Cursor.Descending) {
+			return invalidField("cursor", "the cursor was made for a different order")
+		}
+
+		page.SortBy = page.Cursor.SortBy
+		page.Descending = page.Cursor.Descending
+
+		if !validCursorKeys(*page.Cursor, numericIds) {
+			return invalidField("cursor", "cursor should be the nextCursor of a previous page")
+		}
+	}
+
+	if len(page.SortBy) < 1 {
+		page.SortBy = entities.SortById
+	}
+
+	if !slices.Contains(sortFields, page.SortBy) {
+		return invalidField("sort", "can only sort by %s", strings.Join(sortFields, ", "))
+	}
+
+	return nil
+}
+
+// validCursorKeys checks that the keys of a cursor have the types of the
+// columns they are compared with.
+func validCursorKeys(cursor entities.Cursor, numericIds bool) bool {
+
+	if numericIds {
+		if _, err := strconv.Atoi(cursor.Id); err != nil {
+			return false
+		}
+	}
+
+	if cursor.SortBy == entities.SortByCreatedAt {
+		if _, err := time.Parse(time.RFC3339Nano, cursor.Key); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateResourceFilter(filter entities.ResourceFilter) error {
+
+	var errs []error
+
+	for _, status := range filter.Statuses {
+		if !slices.Contains(resourceStatuses, status) {
+			errs = append(errs, invalidField("status", "the status should be one of %s", strings.Join(resourceStatuses, ", ")))
+			break
+		}
+	}
+
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		errs = append(errs, invalidField("createdAfter", "createdAfter should be before createdBefore"))
+	}
+
+	if filter.IngestedAfter != nil && filter.IngestedBefore != nil && !filter.IngestedAfter.Before(*filter.IngestedBefore) {
+		errs = append(errs, invalidField("ingestedAfter", "ingestedAfter should be before ingestedBefore"))
+	}
+
+	if len(filter.Search) > 100 {
+		errs = append(errs, invalidField("q", "the search text can be 100 characters long"))
+	}
+
+	return errors.Join(errs...)
+}
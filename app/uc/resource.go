@@ -2,6 +2,7 @@ package uc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"time"
@@ -11,22 +12,40 @@ import (
 	"github.com/utsavgupta/knowledge-hub/app/repos"
 )
 
-type ListResourcesUc func(context.Context, string) ([]entities.Resource, error)
+const minRefreshIntervalSeconds = 60
+
+type ListResourcesUc func(context.Context, entities.ResourceFilter, entities.PageRequest) (*entities.ResourcePage, error)
 type AddResourceUc func(context.Context, entities.Resource) (*entities.Resource, error)
 type DeleteResourceUc func(context.Context, string, int) error
+type ReingestResourceUc func(context.Context, string, int, bool) (*entities.Resource, error)
+type GetResourceUc func(context.Context, string, int) (*entities.Resource, error)
+
+// UpdateResourceUc applies a patch to a resource. When a version is given,
+// the resource is only changed if it is still at that version.
+type UpdateResourceUc func(context.Context, string, int, entities.ResourcePatch, *time.Time) (*entities.Resource, error)
 
 func NewListResourcesUc(repo repos.ResourceRepo) ListResourcesUc {
 
-	return func(ctx context.Context, domainId string) ([]entities.Resource, error) {
+	return func(ctx context.Context, filter entities.ResourceFilter, page entities.PageRequest) (*entities.ResourcePage, error) {
 
-		entities, err := repo.List(ctx, domainId)
+		if err := normalizePageRequest(&page, resourceSortFields, true); err != nil {
+			logger.Instance().Debug(ctx, err.Error())
+			return nil, err
+		}
+
+		if err := validateResourceFilter(filter); err != nil {
+			logger.Instance().Debug(ctx, err.Error())
+			return nil, err
+		}
+
+		resources, err := repo.Find(ctx, filter, page)
 
 		if err != nil {
 			logger.Instance().Error(ctx, err.Error())
 			return nil, fmt.Errorf("could not fetch resources list")
 		}
 
-		return entities, nil
+		return resources, nil
 	}
 }
 
@@ -40,7 +59,7 @@ func NewAddResourceUc(resourceRepo repos.ResourceRepo, domainRepo repos.DomainRe
 		}
 
 		if ent, _ := domainRepo.Get(ctx, resource.DomainId); ent == nil {
-			return nil, fmt.Errorf("%w: invalid domain id", ValidationError)
+			return nil, fmt.Errorf("%w: domain %s does not exist", NotFoundError, resource.DomainId)
 		}
 
 		resource.CreatedAt = time.Now()
@@ -57,11 +76,28 @@ func NewAddResourceUc(resourceRepo repos.ResourceRepo, domainRepo repos.DomainRe
 	}
 }
 
-func NewDeleteResourceUc(repo repos.ResourceRepo) DeleteResourceUc {
+func NewDeleteResourceUc(repo repos.ResourceRepo, chunkRepo repos.ChunkRepo) DeleteResourceUc {
 
 	return func(ctx context.Context, domainId string, id int) error {
 
-		err := repo.Delete(ctx, domainId, id)
+		resource, err := repo.Get(ctx, id)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return fmt.Errorf("could not fetch resource")
+		}
+
+		if resource == nil || resource.DomainId != domainId {
+			return fmt.Errorf("%w: resource %d does not exist in domain %s", NotFoundError, id, domainId)
+		}
+
+		// Chunks go first so a failure leaves the resource around to retry the delete.
+		if err := chunkRepo.DeleteByResource(ctx, domainId, id); err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return fmt.Errorf("could not delete resource")
+		}
+
+		err = repo.Delete(ctx, domainId, id)
 
 		if err != nil {
 			logger.Instance().Error(ctx, err.Error())
@@ -72,23 +108,142 @@ func NewDeleteResourceUc(repo repos.ResourceRepo) DeleteResourceUc {
 	}
 }
 
+// NewReingestResourceUc queues a resource for ingestion again. Unless forced,
+// the worker skips re-embedding when the content has not changed.
+func NewReingestResourceUc(repo repos.ResourceRepo) ReingestResourceUc {
+
+	return func(ctx context.Context, domainId string, id int, force bool) (*entities.Resource, error) {
+
+		resource, err := repo.Requeue(ctx, domainId, id, force, time.Now())
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not queue resource for ingestion")
+		}
+
+		if resource != nil {
+			return resource, nil
+		}
+
+		// Nothing was updated, either because the resource does not exist or
+		// because it is being ingested.
+		if resource, err = repo.Get(ctx, id); err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not fetch resource")
+		}
+
+		if resource == nil || resource.DomainId != domainId {
+			return nil, fmt.Errorf("%w: resource %d does not exist in domain %s", NotFoundError, id, domainId)
+		}
+
+		return nil, fmt.Errorf("%w: resource %d is being ingested", ConflictError, id)
+	}
+}
+
+func NewGetResourceUc(repo repos.ResourceRepo) GetResourceUc {
+
+	return func(ctx context.Context, domainId string, id int) (*entities.Resource, error) {
+
+		resource, err := repo.Get(ctx, id)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not fetch resource")
+		}
+
+		if resource == nil || resource.DomainId != domainId {
+			return nil, fmt.Errorf("%w: resource %d does not exist in domain %s", NotFoundError, id, domainId)
+		}
+
+		return resource, nil
+	}
+}
+
+// NewUpdateResourceUc queues a resource for ingestion again when its url
+// changes, since its chunks are of the previous page.
+func NewUpdateResourceUc(repo repos.ResourceRepo) UpdateResourceUc {
+
+	return func(ctx context.Context, domainId string, id int, patch entities.ResourcePatch, version *time.Time) (*entities.Resource, error) {
+
+		resource, err := repo.Get(ctx, id)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not fetch resource")
+		}
+
+		if resource == nil || resource.DomainId != domainId {
+			return nil, fmt.Errorf("%w: resource %d does not exist in domain %s", NotFoundError, id, domainId)
+		}
+
+		current := resource.Version()
+
+		if version != nil && !version.Equal(current) {
+			return nil, fmt.Errorf("%w: resource %d has changed since it was read", PreconditionFailedError, id)
+		}
+
+		previousUrl := resource.Url
+		patch.Apply(resource)
+
+		if err = validateResourceEntity(*resource); err != nil {
+			logger.Instance().Debug(ctx, err.Error())
+			return nil, err
+		}
+
+		if resource.Url != previousUrl {
+
+			if resource.Status == entities.ResourceStatusIngesting {
+				return nil, fmt.Errorf("%w: the url of resource %d cannot change while it is being ingested", ConflictError, id)
+			}
+
+			resource.Status = entities.ResourceStatusNew
+			resource.Attempts = 0
+			resource.LastError = nil
+			resource.NextAttemptAt = nil
+			resource.ContentHash = nil
+		}
+
+		now := time.Now()
+		resource.UpdatedAt = &now
+
+		updated, err := repo.Update(ctx, *resource, current)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return nil, fmt.Errorf("could not update resource")
+		}
+
+		if updated == nil {
+			return nil, fmt.Errorf("%w: resource %d has changed since it was read", PreconditionFailedError, id)
+		}
+
+		return updated, nil
+	}
+}
+
 func validateResourceEntity(resource entities.Resource) error {
 
+	var errs []error
+
 	if len(resource.DomainId) < 2 || len(resource.DomainId) > 15 {
-		return fmt.Errorf("%w: domain id can beetween 2 and 15 characters long.", ValidationError)
+		errs = append(errs, invalidField("domainId", "domain id can beetween 2 and 15 characters long."))
 	}
 
 	if len(resource.Name) > 50 {
-		return fmt.Errorf("%w: the name can be 50 characters long", ValidationError)
+		errs = append(errs, invalidField("name", "the name can be 50 characters long"))
 	}
 
 	if len(resource.Description) > 140 {
-		return fmt.Errorf("%w: the description can be 140 characters long", ValidationError)
+		errs = append(errs, invalidField("description", "the description can be 140 characters long"))
 	}
 
 	if _, err := url.ParseRequestURI(resource.Url); err != nil {
-		return fmt.Errorf("%w: invalid url", ValidationError)
+		errs = append(errs, invalidField("url", "invalid url"))
+	}
+
+	if resource.RefreshIntervalSeconds != nil && *resource.RefreshIntervalSeconds < minRefreshIntervalSeconds {
+		errs = append(errs, invalidField("refreshIntervalSeconds", "the refresh interval should be at least %d seconds", minRefreshIntervalSeconds))
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
@@ -2,6 +2,7 @@ package uc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/utsavgupta/knowledge-hub/app/entities"
@@ -11,21 +12,51 @@ import (
 )
 
 type SearchUc func(context.Context, entities.Query) (*entities.Response, error)
-type DomainStatusValidator func(context.Context, string) error
+type StreamSearchUc func(context.Context, entities.Query, entities.ResponseEventHandler) (*entities.Response, error)
 
-func NewSearchUc(domainStatusValidator DomainStatusValidator, responseRepo repos.ResponseRepo, conceptService services.ConceptService) SearchUc {
+// DomainStatusValidator narrows the requested domains down to the ones with
+// ingested resources. A single domain that cannot be searched is reported as a
+// validation error, whereas federated searches skip such domains.
+type DomainStatusValidator func(context.Context, []string) ([]string, error)
+
+func NewSearchUc(domainStatusValidator DomainStatusValidator, domainRepo repos.DomainRepo, checkBudgetUc CheckBudgetUc, recordUsageUc RecordUsageUc, responseRepo repos.ResponseRepo, conceptService services.ConceptService, condenseService services.CondenseService) SearchUc {
 
 	return func(ctx context.Context, query entities.Query) (*entities.Response, error) {
 
-		if err := domainStatusValidator(ctx, query.DomainId); err != nil {
+		domainIds, err := domainStatusValidator(ctx, query.DomainIds)
+
+		if err != nil {
+			return nil, err
+		}
+
+		query.DomainIds = domainIds
+		query.Model = resolveModel(ctx, domainRepo, domainIds)
+
+		if err := checkBudgetUc(ctx, domainIds); err != nil {
 			return nil, err
 		}
 
+		ctx, usage := services.WithTokenUsage(ctx)
+		defer func() { recordUsageUc(ctx, domainIds, usage.Tokens()) }()
+
+		// Follow-up questions are rewritten first so that concept extraction and
+		// retrieval do not depend on the earlier turns of the conversation.
+		if len(query.History) > 0 {
+
+			question, err := condenseService.Get(ctx, query.History, query.Question)
+
+			if err != nil {
+				logger.Instance().Warn(ctx, fmt.Sprintf("could not condense question, searching with the original: %v", err))
+			} else if len(question) > 0 {
+				query.Question = question
+			}
+		}
+
 		concepts, err := conceptService.Get(ctx, query.Question)
 
 		if err != nil || len(concepts) < 1 {
-			logger.Instance().Error(ctx, err.Error())
-			return nil, fmt.Errorf("could fetch contexts")
+			logger.Instance().Error(ctx, fmt.Sprintf("could not fetch concepts: %v", err))
+			return nil, upstreamError(err, "could not fetch concepts")
 		}
 
 		query.Concepts = concepts
@@ -34,34 +65,186 @@ func NewSearchUc(domainStatusValidator DomainStatusValidator, responseRepo repos
 
 		if err != nil {
 			logger.Instance().Error(ctx, err.Error())
-			err = fmt.Errorf("could not generate answer")
+			err = upstreamError(err, "could not generate answer")
 		}
 
 		return answer, err
 	}
 }
 
-func NewDomainStatusValidator(resourceRepo repos.ResourceRepo) DomainStatusValidator {
+func NewStreamSearchUc(domainStatusValidator DomainStatusValidator, domainRepo repos.DomainRepo, checkBudgetUc CheckBudgetUc, recordUsageUc RecordUsageUc, responseRepo repos.StreamingResponseRepo, conceptService services.ConceptService) StreamSearchUc {
 
-	return func(ctx context.Context, domainId string) error {
+	return func(ctx context.Context, query entities.Query, handler entities.ResponseEventHandler) (*entities.Response, error) {
 
-		resources, err := resourceRepo.List(ctx, domainId)
+		domainIds, err := domainStatusValidator(ctx, query.DomainIds)
+
+		if err != nil {
+			return nil, err
+		}
+
+		query.DomainIds = domainIds
+		query.Model = resolveModel(ctx, domainRepo, domainIds)
+
+		if err := checkBudgetUc(ctx, domainIds); err != nil {
+			return nil, err
+		}
+
+		ctx, usage := services.WithTokenUsage(ctx)
+		defer func() { recordUsageUc(ctx, domainIds, usage.Tokens()) }()
+
+		concepts, err := conceptService.Get(ctx, query.Question)
+
+		if err != nil || len(concepts) < 1 {
+			logger.Instance().Error(ctx, fmt.Sprintf("could not fetch concepts: %v", err))
+			return nil, upstreamError(err, "could not fetch concepts")
+		}
+
+		query.Concepts = concepts
+
+		if err := handler(entities.ResponseEvent{Type: entities.ResponseEventConcepts, Concepts: concepts}); err != nil {
+			return nil, err
+		}
+
+		answer, err := responseRepo.Stream(ctx, query, handler)
 
 		if err != nil {
 			logger.Instance().Error(ctx, err.Error())
-			return fmt.Errorf("could not fetch resources from database: %w", err)
+			return nil, upstreamError(err, "could not generate answer")
 		}
 
-		if len(resources) < 1 {
-			return fmt.Errorf("%w: either the domain id %s does not exist, or it contains no resources", ValidationError, domainId)
+		if err := handler(entities.ResponseEvent{Type: entities.ResponseEventDone, Response: answer}); err != nil {
+			return nil, err
+		}
+
+		return answer, nil
+	}
+}
+
+// resolveModel returns the model a single domain overrides the default with.
+// Federated searches always answer with the default model.
+func resolveModel(ctx context.Context, domainRepo repos.DomainRepo, domainIds []string) string {
+
+	if len(domainIds) != 1 {
+		return ""
+	}
+
+	domain, err := domainRepo.Get(ctx, domainIds[0])
+
+	if err != nil {
+		logger.Instance().Warn(ctx, fmt.Sprintf("could not fetch model of domain %s, answering with the default: %v", domainIds[0], err))
+		return ""
+	}
+
+	if domain == nil || domain.Model == nil {
+		return ""
+	}
+
+	return *domain.Model
+}
+
+func NewDomainStatusValidator(domainRepo repos.DomainRepo, resourceRepo repos.ResourceRepo) DomainStatusValidator {
+
+	return func(ctx context.Context, domainIds []string) ([]string, error) {
+
+		if len(domainIds) == 1 && domainIds[0] == entities.AllDomains {
+
+			domains, err := domainRepo.List(ctx)
+
+			if err != nil {
+				logger.Instance().Error(ctx, err.Error())
+				return nil, fmt.Errorf("could not fetch domains from database: %w", err)
+			}
+
+			domainIds = make([]string, 0, len(domains))
+
+			for _, domain := range domains {
+				// Searching every domain means every domain the caller can access.
+				if authorizeRead(ctx, domain.Id) == nil {
+					domainIds = append(domainIds, domain.Id)
+				}
+			}
+		} else {
+
+			for _, domainId := range domainIds {
+
+				if err := authorizeRead(ctx, domainId); err != nil {
+					return nil, err
+				}
+			}
 		}
 
-		for _, resource := range resources {
-			if resource.Status == entities.ResourceStatusIngested {
-				return nil
+		searchable := make([]string, 0, len(domainIds))
+		var lastErr error
+
+		for _, domainId := range domainIds {
+
+			if err := validateDomainStatus(ctx, domainRepo, resourceRepo, domainId); err != nil {
+
+				if !errors.Is(err, ValidationError) && !errors.Is(err, NotFoundError) {
+					return nil, err
+				}
+
+				logger.Instance().Debug(ctx, err.Error())
+				lastErr = err
+				continue
 			}
+
+			searchable = append(searchable, domainId)
+		}
+
+		if len(searchable) > 0 {
+			return searchable, nil
 		}
 
-		return fmt.Errorf("%w: none of the resources have been ingested for domain %s", ValidationError, domainId)
+		if len(domainIds) == 1 {
+			return nil, lastErr
+		}
+
+		return nil, invalidField("domain_id", "none of the requested domains have ingested resources")
 	}
 }
+
+func validateDomainStatus(ctx context.Context, domainRepo repos.DomainRepo, resourceRepo repos.ResourceRepo, domainId string) error {
+
+	resources, err := resourceRepo.List(ctx, domainId)
+
+	if err != nil {
+		logger.Instance().Error(ctx, err.Error())
+		return fmt.Errorf("could not fetch resources from database: %w", err)
+	}
+
+	if len(resources) < 1 {
+
+		domain, err := domainRepo.Get(ctx, domainId)
+
+		if err != nil {
+			logger.Instance().Error(ctx, err.Error())
+			return fmt.Errorf("could not fetch domain from database: %w", err)
+		}
+
+		if domain == nil {
+			return fmt.Errorf("%w: domain %s does not exist", NotFoundError, domainId)
+		}
+
+		return invalidField("domain_id", "domain %s contains no resources", domainId)
+	}
+
+	var failed *entities.Resource
+
+	for i, resource := range resources {
+		// Resources being refreshed keep serving the chunks of their previous ingestion.
+		if resource.Status == entities.ResourceStatusIngested || resource.IngestionCompletedAt != nil {
+			return nil
+		}
+
+		if resource.Status == entities.ResourceStatusFailed && resource.LastError != nil {
+			failed = &resources[i]
+		}
+	}
+
+	if failed != nil {
+		return invalidField("domain_id", "none of the resources have been ingested for domain %s. resource %d failed with: %s", domainId, failed.Id, *failed.LastError)
+	}
+
+	return invalidField("domain_id", "none of the resources have been ingested for domain %s", domainId)
+}